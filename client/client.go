@@ -9,7 +9,7 @@ import (
 
 	pb "github.com/kingztech2019/proto_grpc/proto_inventory/grpc-inventory-proto"
 	"google.golang.org/grpc"
-	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
 )
 
 func main() {
@@ -23,13 +23,16 @@ func main() {
 
 	createProduct(client)
 	getProduct(client, "product-123")
+	listProducts(client)
 	updateProduct(client)
-	deleteProduct(client, "product-123")
 
 	createOrder(client)
 	getOrder(client, "order-123")
 	updateOrder(client)
+	confirmOrder(client, "order-123")
 	deleteOrder(client, "order-123")
+
+	deleteProduct(client, "product-123")
 }
 
 // Helper function for formatted JSON output
@@ -48,12 +51,12 @@ func createProduct(client pb.InventoryServiceClient) {
 	product := &pb.Product{
 		Id:             "product-123",
 		Name:           "Sample Product",
-		Price:          9.99,
+		PriceMoney:     &pb.Money{CurrencyCode: "USD", Units: 9, Nanos: 990_000_000},
 		InventoryLevel: 100,
 	}
 
-	req := &pb.UpdateProductRequest{Product: product}
-	res, err := client.UpdateProduct(ctx, req)
+	req := &pb.CreateProductRequest{Product: product}
+	res, err := client.CreateProduct(ctx, req)
 	if err != nil {
 		log.Fatalf("Failed to create product: %v", err)
 	}
@@ -72,18 +75,33 @@ func getProduct(client pb.InventoryServiceClient, productId string) {
 	printFormattedResponse("Get Product Response", res)
 }
 
+func listProducts(client pb.InventoryServiceClient) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	req := &pb.ListProductsRequest{PageSize: 10, NameContains: "product", OrderBy: "price desc"}
+	res, err := client.ListProducts(ctx, req)
+	if err != nil {
+		log.Fatalf("Failed to list products: %v", err)
+	}
+	printFormattedResponse("List Products Response", res)
+}
+
 func updateProduct(client pb.InventoryServiceClient) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 	defer cancel()
 
+	// Only the name and price change; the mask leaves inventory_level alone.
 	product := &pb.Product{
-		Id:             "product-123",
-		Name:           "Updated Product",
-		Price:          12.99,
-		InventoryLevel: 150,
+		Id:         "product-123",
+		Name:       "Updated Product",
+		PriceMoney: &pb.Money{CurrencyCode: "USD", Units: 12, Nanos: 990_000_000},
 	}
 
-	req := &pb.UpdateProductRequest{Product: product}
+	req := &pb.UpdateProductRequest{
+		Product:    product,
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"name", "price_money"}},
+	}
 	res, err := client.UpdateProduct(ctx, req)
 	if err != nil {
 		log.Fatalf("Failed to update product: %v", err)
@@ -108,10 +126,10 @@ func createOrder(client pb.InventoryServiceClient) {
 	defer cancel()
 
 	order := &pb.Order{
-		Id:        "order-123",
-		ProductId: "product-123",
-		Quantity:  5,
-		OrderDate: timestamppb.Now(),
+		Id: "order-123",
+		Lines: []*pb.OrderLine{
+			{ProductId: "product-123", Quantity: 5},
+		},
 	}
 
 	req := &pb.CreateOrderRequest{Order: order}
@@ -139,10 +157,10 @@ func updateOrder(client pb.InventoryServiceClient) {
 	defer cancel()
 
 	order := &pb.Order{
-		Id:        "order-123",
-		ProductId: "product-123",
-		Quantity:  10,
-		OrderDate: timestamppb.Now(),
+		Id: "order-123",
+		Lines: []*pb.OrderLine{
+			{ProductId: "product-123", Quantity: 10},
+		},
 	}
 
 	req := &pb.UpdateOrderRequest{Order: order}
@@ -153,6 +171,18 @@ func updateOrder(client pb.InventoryServiceClient) {
 	printFormattedResponse("Update Order Response", res)
 }
 
+func confirmOrder(client pb.InventoryServiceClient, orderId string) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	req := &pb.OrderTransitionRequest{OrderId: orderId, Actor: "sample-client", Reason: "payment received"}
+	res, err := client.ConfirmOrder(ctx, req)
+	if err != nil {
+		log.Fatalf("Failed to confirm order: %v", err)
+	}
+	printFormattedResponse("Confirm Order Response", res)
+}
+
 func deleteOrder(client pb.InventoryServiceClient, orderId string) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 	defer cancel()
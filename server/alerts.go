@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	pb "github.com/kingztech2019/proto_grpc/proto_inventory/grpc-inventory-proto"
+	"github.com/kingztech2019/proto_grpc/proto_inventory/store"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const webhookTimeout = 10 * time.Second
+
+// notifier delivers alerts outside the service. The server calls Notify
+// once per alert, in alert order, from a single goroutine.
+type notifier interface {
+	Notify(ctx context.Context, alert *pb.Alert) error
+}
+
+// logNotifier writes alerts to the server log
+type logNotifier struct{}
+
+func (logNotifier) Notify(ctx context.Context, alert *pb.Alert) error {
+	log.Printf("%s alert: product %q is at %d units (reorder point %d, reorder quantity %d)",
+		alert.Type, alert.ProductId, alert.InventoryLevel, alert.ReorderPoint, alert.ReorderQuantity)
+	return nil
+}
+
+// webhookNotifier POSTs each alert as JSON to a URL
+type webhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+func newWebhookNotifier(url string) *webhookNotifier {
+	return &webhookNotifier{url: url, client: &http.Client{Timeout: webhookTimeout}}
+}
+
+func (n *webhookNotifier) Notify(ctx context.Context, alert *pb.Alert) error {
+	body, err := protojson.Marshal(alert)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook returned %s", res.Status)
+	}
+	return nil
+}
+
+// isLowStock reports whether p is at or below its reorder point
+func isLowStock(p *pb.Product) bool {
+	return p != nil && p.ReorderPoint > 0 && p.InventoryLevel <= p.ReorderPoint
+}
+
+// recordAlert appends a LOW_STOCK alert if a product write takes the
+// product from above its reorder point to at or below it. Writes that leave
+// it low raise nothing, so each crossing alerts once.
+func recordAlert(tx store.Tx, before, after *pb.Product, cause string) error {
+	if !isLowStock(after) || isLowStock(before) {
+		return nil
+	}
+	return tx.Alerts().Append(&pb.Alert{
+		Type:            pb.AlertType_LOW_STOCK,
+		ProductId:       after.Id,
+		InventoryLevel:  after.InventoryLevel,
+		ReorderPoint:    after.ReorderPoint,
+		ReorderQuantity: after.ReorderQuantity,
+		Cause:           cause,
+		Time:            timestamppb.Now(),
+	})
+}
+
+// tailAlerts calls fn for every alert after the given sequence as alerts
+// are committed, until fn fails or ctx is done
+func (s *server) tailAlerts(ctx context.Context, after uint64, fn func(*pb.Alert) error) error {
+	poll := time.NewTicker(watchPollInterval)
+	defer poll.Stop()
+	for {
+		// Take the wake channel before reading so an alert committed
+		// between the read and the wait is not missed.
+		wake := s.events.wait()
+		var alerts []*pb.Alert
+		err := s.store.View(ctx, func(tx store.Tx) error {
+			var err error
+			alerts, err = tx.Alerts().ListAfter(after, watchBatchSize)
+			return err
+		})
+		if err != nil {
+			return storeError(err)
+		}
+		for _, alert := range alerts {
+			after = alert.Sequence
+			alert.ResumeToken = resumeToken(alert.Sequence)
+			if err := fn(alert); err != nil {
+				return err
+			}
+		}
+		if len(alerts) == watchBatchSize {
+			continue
+		}
+
+		select {
+		case <-wake:
+		case <-poll.C:
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		}
+	}
+}
+
+// lastAlert returns the sequence of the newest alert
+func (s *server) lastAlert(ctx context.Context) (uint64, error) {
+	var last uint64
+	err := s.store.View(ctx, func(tx store.Tx) error {
+		var err error
+		last, err = tx.Alerts().Last()
+		return err
+	})
+	return last, err
+}
+
+// SubscribeAlerts streams alerts as they are raised. A client that
+// reconnects with the resume_token of the last alert it saw receives every
+// alert after it.
+func (s *server) SubscribeAlerts(req *pb.SubscribeAlertsRequest, stream grpc.ServerStreamingServer[pb.Alert]) error {
+	ctx := stream.Context()
+	want := make(map[string]bool, len(req.ProductIds))
+	for _, id := range req.ProductIds {
+		want[id] = true
+	}
+
+	last, err := s.lastAlert(ctx)
+	if err != nil {
+		return storeError(err)
+	}
+	after := last
+	if req.ResumeToken != "" {
+		after, err = strconv.ParseUint(req.ResumeToken, 10, 64)
+		if err != nil {
+			return invalidArgument("resume_token", "malformed token")
+		}
+		if after > last {
+			return invalidArgument("resume_token", "token is ahead of the alert log")
+		}
+	}
+
+	return s.tailAlerts(ctx, after, func(alert *pb.Alert) error {
+		if len(want) > 0 && !want[alert.ProductId] {
+			return nil
+		}
+		return stream.Send(alert)
+	})
+}
+
+// dispatchAlerts hands alerts raised while the server runs to the notifier
+// until ctx is done. A failed delivery is logged and not retried.
+func (s *server) dispatchAlerts(ctx context.Context) {
+	last, err := s.lastAlert(ctx)
+	if err != nil {
+		log.Printf("Failed to start alert notifier: %v", err)
+		return
+	}
+	err = s.tailAlerts(ctx, last, func(alert *pb.Alert) error {
+		if err := s.notifier.Notify(ctx, alert); err != nil {
+			log.Printf("Failed to deliver alert %d: %v", alert.Sequence, err)
+		}
+		return nil
+	})
+	if status.Code(err) != codes.Canceled {
+		log.Printf("Alert notifier stopped: %v", err)
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	pb "github.com/kingztech2019/proto_grpc/proto_inventory/grpc-inventory-proto"
+	"github.com/kingztech2019/proto_grpc/proto_inventory/store"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// backorderSources are the movement reasons that bring new stock into a
+// warehouse, which goes to waiting backorders first
+var backorderSources = map[pb.StockMovementReason]bool{
+	pb.StockMovementReason_RECEIVED:          true,
+	pb.StockMovementReason_COUNT_CORRECTION:  true,
+	pb.StockMovementReason_TRANSFER_IN:       true,
+	pb.StockMovementReason_TRANSFER_REVERSED: true,
+}
+
+// backordered returns how many of the order's units are waiting for stock
+func backordered(order *pb.Order) int32 {
+	var n int32
+	for _, line := range order.Lines {
+		n += line.BackorderedQuantity
+	}
+	return n
+}
+
+// backorderLines applies the order's backorder policy to the lines its
+// warehouse cannot supply in full. credit counts units of each product the
+// order already holds there and gives back in the same change. Under
+// REJECT every line stays whole and reserving reports any shortfall.
+func (s *server) backorderLines(tx store.Tx, order *pb.Order, credit map[string]int32) error {
+	for _, line := range order.Lines {
+		line.BackorderedQuantity = 0
+	}
+	policy := order.BackorderPolicy
+	if policy != pb.BackorderPolicy_BACKORDER_POLICY_BACKORDER && policy != pb.BackorderPolicy_BACKORDER_POLICY_PARTIAL {
+		return nil
+	}
+
+	available := make(map[string]int32)
+	var lines []*pb.OrderLine
+	for _, line := range order.Lines {
+		n, ok := available[line.ProductId]
+		if !ok {
+			product, err := tx.Products().Get(line.ProductId)
+			if errors.Is(err, store.ErrNotFound) {
+				return notFound(productResource, line.ProductId)
+			}
+			if err != nil {
+				return err
+			}
+			if n, err = s.allocatable(tx, product, order.WarehouseId); err != nil {
+				return err
+			}
+			n += credit[line.ProductId]
+		}
+		reserved := min(line.Quantity, max(n, 0))
+		available[line.ProductId] = n - reserved
+		if len(line.SerialNumbers) > int(reserved) {
+			return failedPrecondition("STOCK", line.ProductId, fmt.Sprintf(
+				"only %d units of product %q can be reserved for its %d serial numbers",
+				reserved, line.ProductId, len(line.SerialNumbers)))
+		}
+		if policy == pb.BackorderPolicy_BACKORDER_POLICY_PARTIAL {
+			line.Quantity = reserved
+		} else {
+			line.BackorderedQuantity = line.Quantity - reserved
+		}
+		if line.Quantity > 0 {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 {
+		return failedPrecondition("STOCK", order.Id, fmt.Sprintf(
+			"none of the products on order %q are in stock at warehouse %q", order.Id, order.WarehouseId))
+	}
+	order.Lines = lines
+	normalizeLines(order)
+	return nil
+}
+
+// fillBackorders reserves a product's stock at a warehouse for the orders
+// there waiting on it, oldest order_date first, until the stock or the
+// orders run out. Each order given stock gets a BACKORDER_FILLED event.
+func (s *server) fillBackorders(tx store.Tx, productID, warehouseID, cause string) error {
+	orders, err := tx.Orders().Query(store.OrderQuery{
+		ProductID: productID,
+		Statuses: []pb.OrderStatus{
+			pb.OrderStatus_ORDER_STATUS_PENDING,
+			pb.OrderStatus_ORDER_STATUS_CONFIRMED,
+			pb.OrderStatus_ORDER_STATUS_PICKING,
+		},
+		Backordered: true,
+	})
+	if err != nil {
+		return err
+	}
+	for _, order := range orders {
+		s.normalizeOrder(order)
+		if order.WarehouseId != warehouseID {
+			continue
+		}
+		product, err := s.getProduct(tx, productID)
+		if err != nil {
+			return err
+		}
+		available, err := s.allocatable(tx, product, warehouseID)
+		if err != nil || available <= 0 {
+			return err
+		}
+		var filled int32
+		for _, line := range order.Lines {
+			if line.ProductId == productID {
+				n := min(line.BackorderedQuantity, available-filled)
+				line.BackorderedQuantity -= n
+				filled += n
+			}
+		}
+		if err := s.reserveStock(tx, order, warehouseID, map[string]int32{productID: filled}, cause); err != nil {
+			return err
+		}
+		order.Version++
+		if err := tx.Orders().Update(order); err != nil {
+			return err
+		}
+		err = s.appendEvent(tx, &pb.InventoryEvent{
+			Type:           pb.InventoryEventType_BACKORDER_FILLED,
+			ProductId:      productID,
+			OrderId:        order.Id,
+			FilledQuantity: filled,
+			Cause:          cause,
+			Time:           timestamppb.Now(),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
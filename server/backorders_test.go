@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	pb "github.com/kingztech2019/proto_grpc/proto_inventory/grpc-inventory-proto"
+	"google.golang.org/grpc/codes"
+)
+
+func TestBackorderPolicies(t *testing.T) {
+	s := newTestServer(t, nil)
+	ctx := context.Background()
+	createProduct(t, s, "a", 4)
+	createProduct(t, s, "b", 0)
+
+	_, err := s.CreateOrder(ctx, &pb.CreateOrderRequest{
+		Order:           &pb.Order{Lines: []*pb.OrderLine{line("a", 6)}},
+		BackorderPolicy: pb.BackorderPolicy_BACKORDER_POLICY_REJECT,
+	})
+	wantCode(t, err, codes.FailedPrecondition)
+	_, err = s.CreateOrder(ctx, &pb.CreateOrderRequest{
+		Order:           &pb.Order{Lines: []*pb.OrderLine{line("b", 1)}},
+		BackorderPolicy: pb.BackorderPolicy_BACKORDER_POLICY_PARTIAL,
+	})
+	wantCode(t, err, codes.FailedPrecondition)
+
+	partial := createOrder(t, s, pb.BackorderPolicy_BACKORDER_POLICY_PARTIAL, line("a", 1), line("b", 2))
+	if len(partial.Lines) != 1 || partial.Lines[0].ProductId != "a" || partial.Lines[0].Quantity != 1 {
+		t.Errorf("partial order lines = %v, want only a x1", partial.Lines)
+	}
+
+	backordered := createOrder(t, s, pb.BackorderPolicy_BACKORDER_POLICY_BACKORDER, line("a", 5))
+	if got := backordered.Lines[0].BackorderedQuantity; got != 2 {
+		t.Errorf("backordered quantity = %d, want 2", got)
+	}
+	if got := level(t, s, "a"); got != 0 {
+		t.Errorf("level = %d, want 0", got)
+	}
+	advance(t, backordered.Id, s.ConfirmOrder, s.StartPicking)
+	_, err = s.ShipOrder(ctx, &pb.OrderTransitionRequest{OrderId: backordered.Id, Actor: "test"})
+	wantCode(t, err, codes.FailedPrecondition)
+
+	// Stock arriving goes to the waiting order before anything else.
+	receive(t, s, "a", 3, 0)
+	res, err := s.GetOrder(ctx, &pb.GetOrderRequest{OrderId: backordered.Id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := res.Order.Lines[0].BackorderedQuantity; got != 0 {
+		t.Errorf("backordered quantity after a receipt = %d, want 0", got)
+	}
+	if got := level(t, s, "a"); got != 1 {
+		t.Errorf("level after filling the backorder = %d, want 1", got)
+	}
+	advance(t, backordered.Id, s.ShipOrder)
+}
@@ -0,0 +1,384 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	pb "github.com/kingztech2019/proto_grpc/proto_inventory/grpc-inventory-proto"
+	"github.com/kingztech2019/proto_grpc/proto_inventory/store"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// getCount loads a cycle count, reporting a missing one as NotFound
+func getCount(tx store.Tx, id string) (*pb.CycleCount, error) {
+	count, err := tx.Counts().Get(id)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, notFound(countResource, id)
+	}
+	return count, err
+}
+
+// isOpen fails unless the count is still open
+func isOpen(count *pb.CycleCount) error {
+	if count.Status != pb.CountStatus_COUNT_STATUS_OPEN {
+		return failedPrecondition("COUNT_STATUS", count.Id, fmt.Sprintf("count %q is %s", count.Id, count.Status))
+	}
+	return nil
+}
+
+// varianceValue prices a variance at the product's unit price
+func varianceValue(price *pb.Money, variance int32) (*pb.Money, error) {
+	value, err := mulMoney(price, max(variance, -variance))
+	if err != nil || variance >= 0 {
+		return value, err
+	}
+	value.Units, value.Nanos = -value.Units, -value.Nanos
+	return value, nil
+}
+
+// onHand returns how many units of product are physically at a warehouse:
+// its stock plus the units that orders and holds there have taken out of
+// it but not shipped, which a count still finds on the shelf
+func (s *server) onHand(tx store.Tx, product *pb.Product, warehouseID string) (int32, error) {
+	n, err := s.stockAt(tx, product, warehouseID)
+	if err != nil {
+		return 0, err
+	}
+	orders, err := tx.Orders().Query(store.OrderQuery{
+		ProductID: product.Id,
+		Statuses: []pb.OrderStatus{
+			pb.OrderStatus_ORDER_STATUS_PENDING,
+			pb.OrderStatus_ORDER_STATUS_CONFIRMED,
+			pb.OrderStatus_ORDER_STATUS_PICKING,
+		},
+	})
+	if err != nil {
+		return 0, err
+	}
+	for _, order := range orders {
+		s.normalizeOrder(order)
+		if order.WarehouseId == warehouseID && holdsStock(order) {
+			n += lineDeltas(order, false)[product.Id]
+		}
+	}
+	holds, err := tx.Holds().ListActive()
+	if err != nil {
+		return 0, err
+	}
+	for _, hold := range holds {
+		if hold.WarehouseId == warehouseID {
+			n += holdDeltas(hold, false)[product.Id]
+		}
+	}
+	return n, nil
+}
+
+// StartCount opens a cycle count of some products at a warehouse and
+// snapshots how many units of each it has on hand
+func (s *server) StartCount(ctx context.Context, req *pb.StartCountRequest) (*pb.CycleCount, error) {
+	c := req.Count
+	switch {
+	case c == nil:
+		return nil, invalidArgument("count", "is required")
+	case len(c.Lines) == 0:
+		return nil, invalidArgument("count.lines", "at least one line is required")
+	}
+	seen := make(map[string]bool, len(c.Lines))
+	for i, line := range c.Lines {
+		name := fmt.Sprintf("count.lines[%d]", i)
+		switch {
+		case line == nil:
+			return nil, invalidArgument(name, "is required")
+		case line.ProductId == "":
+			return nil, invalidArgument(name+".product_id", "is required")
+		case seen[line.ProductId]:
+			return nil, invalidArgument(name+".product_id", fmt.Sprintf("product %q is listed twice", line.ProductId))
+		}
+		seen[line.ProductId] = true
+	}
+	key, err := idempotencyKey(ctx, req.IdempotencyKey)
+	if err != nil {
+		return nil, err
+	}
+	count := &pb.CycleCount{Id: c.Id, WarehouseId: c.WarehouseId, Note: c.Note}
+	if count.WarehouseId == "" {
+		count.WarehouseId = s.defaultWarehouse
+	}
+	if count.Id == "" {
+		if count.Id, err = newID(); err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to generate count id: %v", err)
+		}
+	}
+	for _, line := range c.Lines {
+		count.Lines = append(count.Lines, &pb.CountLine{ProductId: line.ProductId})
+	}
+	slices.SortFunc(count.Lines, func(a, b *pb.CountLine) int { return strings.Compare(a.ProductId, b.ProductId) })
+
+	res := &pb.CycleCount{}
+	err = s.mutate(ctx, key, req, res, func(tx store.Tx) error {
+		if _, err := getWarehouse(tx, count.WarehouseId); err != nil {
+			return err
+		}
+		open, err := tx.Counts().ListOpen(count.WarehouseId)
+		if err != nil {
+			return err
+		}
+		for _, other := range open {
+			for _, line := range other.Lines {
+				if seen[line.ProductId] {
+					return failedPrecondition("COUNT_OPEN", line.ProductId, fmt.Sprintf(
+						"product %q is already being counted at warehouse %q by count %q",
+						line.ProductId, count.WarehouseId, other.Id))
+				}
+			}
+		}
+		for _, line := range count.Lines {
+			product, err := s.getProduct(tx, line.ProductId)
+			if err != nil {
+				return err
+			}
+			if line.ExpectedQuantity, err = s.onHand(tx, product, count.WarehouseId); err != nil {
+				return err
+			}
+		}
+		count.Status = pb.CountStatus_COUNT_STATUS_OPEN
+		count.Version = 1
+		count.CreateTime = timestamppb.Now()
+		err = tx.Counts().Create(count)
+		if errors.Is(err, store.ErrAlreadyExists) {
+			return alreadyExists(countResource, count.Id)
+		}
+		if err != nil {
+			return err
+		}
+		proto.Reset(res)
+		proto.Merge(res, count)
+		return nil
+	})
+	if err != nil {
+		return nil, storeError(err)
+	}
+	return res, nil
+}
+
+// GetCount fetches a cycle count by its ID
+func (s *server) GetCount(ctx context.Context, req *pb.GetCountRequest) (*pb.CycleCount, error) {
+	var count *pb.CycleCount
+	err := s.store.View(ctx, func(tx store.Tx) error {
+		var err error
+		count, err = getCount(tx, req.CountId)
+		return err
+	})
+	if err != nil {
+		return nil, storeError(err)
+	}
+	return count, nil
+}
+
+// SubmitCount records counted quantities on an open count. The units on
+// hand are snapshotted again for each line counted, so orders shipped and
+// stock moved since the count started are not mistaken for variance.
+func (s *server) SubmitCount(ctx context.Context, req *pb.SubmitCountRequest) (*pb.CycleCount, error) {
+	switch {
+	case req.CountId == "":
+		return nil, invalidArgument("count_id", "is required")
+	case len(req.Entries) == 0:
+		return nil, invalidArgument("entries", "at least one entry is required")
+	}
+	seen := make(map[string]bool, len(req.Entries))
+	for i, entry := range req.Entries {
+		name := fmt.Sprintf("entries[%d]", i)
+		switch {
+		case entry == nil:
+			return nil, invalidArgument(name, "is required")
+		case entry.ProductId == "":
+			return nil, invalidArgument(name+".product_id", "is required")
+		case entry.CountedQuantity < 0:
+			return nil, invalidArgument(name+".counted_quantity", "must not be negative")
+		case seen[entry.ProductId]:
+			return nil, invalidArgument(name+".product_id", fmt.Sprintf("product %q is listed twice", entry.ProductId))
+		}
+		seen[entry.ProductId] = true
+	}
+	key, err := idempotencyKey(ctx, req.IdempotencyKey)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &pb.CycleCount{}
+	err = s.mutate(ctx, key, req, res, func(tx store.Tx) error {
+		count, err := getCount(tx, req.CountId)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(countResource, count.Id, req.ExpectedVersion, count.Version); err != nil {
+			return err
+		}
+		if err := isOpen(count); err != nil {
+			return err
+		}
+		lines := make(map[string]*pb.CountLine, len(count.Lines))
+		for _, line := range count.Lines {
+			lines[line.ProductId] = line
+		}
+		for i, entry := range req.Entries {
+			line, ok := lines[entry.ProductId]
+			if !ok {
+				return invalidArgument(fmt.Sprintf("entries[%d].product_id", i),
+					fmt.Sprintf("product %q is not part of the count", entry.ProductId))
+			}
+			line.CountedQuantity, line.Counted = entry.CountedQuantity, true
+			product, err := tx.Products().Get(line.ProductId)
+			if errors.Is(err, store.ErrNotFound) {
+				continue // deleted during the count; it is not adjusted
+			}
+			if err != nil {
+				return err
+			}
+			if line.ExpectedQuantity, err = s.onHand(tx, product, count.WarehouseId); err != nil {
+				return err
+			}
+		}
+		count.Version++
+		if err := tx.Counts().Update(count); err != nil {
+			return err
+		}
+		proto.Reset(res)
+		proto.Merge(res, count)
+		return nil
+	})
+	if err != nil {
+		return nil, storeError(err)
+	}
+	return res, nil
+}
+
+// FinalizeCount closes an open count and posts the variance of each counted
+// line to stock as a count correction, except for serialized products
+func (s *server) FinalizeCount(ctx context.Context, req *pb.FinalizeCountRequest) (*pb.CycleCount, error) {
+	if req.CountId == "" {
+		return nil, invalidArgument("count_id", "is required")
+	}
+	key, err := idempotencyKey(ctx, req.IdempotencyKey)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &pb.CycleCount{}
+	err = s.mutate(ctx, key, req, res, func(tx store.Tx) error {
+		count, err := getCount(tx, req.CountId)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(countResource, count.Id, req.ExpectedVersion, count.Version); err != nil {
+			return err
+		}
+		if err := isOpen(count); err != nil {
+			return err
+		}
+		if _, err := getWarehouse(tx, count.WarehouseId); err != nil {
+			return err
+		}
+		for _, line := range count.Lines {
+			if !line.Counted {
+				continue
+			}
+			product, err := tx.Products().Get(line.ProductId)
+			if errors.Is(err, store.ErrNotFound) {
+				continue // deleted during the count; nothing to adjust
+			}
+			if err != nil {
+				return err
+			}
+			normalizeProductPrice(product, s.currency)
+			line.Variance = line.CountedQuantity - line.ExpectedQuantity
+			if line.VarianceValue, err = varianceValue(product.PriceMoney, line.Variance); err != nil {
+				return err
+			}
+			// The variance applies to stock as it is now, so movements made
+			// since the line was counted stand. Reservations are not
+			// touched; only unreserved stock is adjusted.
+			current, err := s.stockAt(tx, product, count.WarehouseId)
+			if err != nil {
+				return err
+			}
+			line.Adjustment = max(line.Variance, -current)
+			if product.Serialized {
+				// Serialized units are found or written off one by one with
+				// ReceiveSerials and AdjustStock, not in bulk.
+				line.Adjustment = 0
+			}
+			if line.Adjustment == 0 {
+				continue
+			}
+			movement := &pb.StockMovement{
+				WarehouseId: count.WarehouseId,
+				Delta:       line.Adjustment,
+				Reason:      pb.StockMovementReason_COUNT_CORRECTION,
+				Note:        count.Note,
+				CountId:     count.Id,
+				Cause:       "FinalizeCount",
+			}
+			if _, err := s.moveStock(tx, product, movement); err != nil {
+				return err
+			}
+			line.MovementSequence = movement.Sequence
+		}
+		count.Status = pb.CountStatus_COUNT_STATUS_FINALIZED
+		count.Version++
+		count.FinalizeTime = timestamppb.Now()
+		if err := tx.Counts().Update(count); err != nil {
+			return err
+		}
+		proto.Reset(res)
+		proto.Merge(res, count)
+		return nil
+	})
+	if err != nil {
+		return nil, storeError(err)
+	}
+	return res, nil
+}
+
+// CancelCount closes an open count without changing stock
+func (s *server) CancelCount(ctx context.Context, req *pb.CancelCountRequest) (*pb.CycleCount, error) {
+	if req.CountId == "" {
+		return nil, invalidArgument("count_id", "is required")
+	}
+	key, err := idempotencyKey(ctx, req.IdempotencyKey)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &pb.CycleCount{}
+	err = s.mutate(ctx, key, req, res, func(tx store.Tx) error {
+		count, err := getCount(tx, req.CountId)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(countResource, count.Id, req.ExpectedVersion, count.Version); err != nil {
+			return err
+		}
+		if err := isOpen(count); err != nil {
+			return err
+		}
+		count.Status = pb.CountStatus_COUNT_STATUS_CANCELLED
+		count.Version++
+		if err := tx.Counts().Update(count); err != nil {
+			return err
+		}
+		proto.Reset(res)
+		proto.Merge(res, count)
+		return nil
+	})
+	if err != nil {
+		return nil, storeError(err)
+	}
+	return res, nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	pb "github.com/kingztech2019/proto_grpc/proto_inventory/grpc-inventory-proto"
+)
+
+func TestCycleCount(t *testing.T) {
+	s := newTestServer(t, nil)
+	ctx := context.Background()
+	createProduct(t, s, "a", 10)
+	createProduct(t, s, "b", 4)
+	createOrder(t, s, 0, line("a", 3))
+
+	count, err := s.StartCount(ctx, &pb.StartCountRequest{Count: &pb.CycleCount{
+		Lines: []*pb.CountLine{{ProductId: "a"}, {ProductId: "b"}},
+	}})
+	if err != nil {
+		t.Fatalf("StartCount: %v", err)
+	}
+	// Reserved units are still on the shelf.
+	if got := count.Lines[0].ExpectedQuantity; got != 10 {
+		t.Errorf("expected quantity of a = %d, want 10", got)
+	}
+
+	// Movements while the count is open stand; the count re-snapshots the
+	// lines it is given.
+	receive(t, s, "a", 2, 0)
+	count, err = s.SubmitCount(ctx, &pb.SubmitCountRequest{CountId: count.Id, Entries: []*pb.CountEntry{
+		{ProductId: "a", CountedQuantity: 9},
+	}})
+	if err != nil {
+		t.Fatalf("SubmitCount: %v", err)
+	}
+	if got := count.Lines[0].ExpectedQuantity; got != 12 {
+		t.Errorf("expected quantity of a after submitting = %d, want 12", got)
+	}
+	receive(t, s, "b", 1, 0)
+
+	count, err = s.FinalizeCount(ctx, &pb.FinalizeCountRequest{CountId: count.Id})
+	if err != nil {
+		t.Fatalf("FinalizeCount: %v", err)
+	}
+	a, b := count.Lines[0], count.Lines[1]
+	if a.Variance != -3 || a.Adjustment != -3 || a.MovementSequence == 0 {
+		t.Errorf("line a = %v, want a variance and adjustment of -3", a)
+	}
+	if b.Counted || b.Adjustment != 0 {
+		t.Errorf("uncounted line b = %v, want no adjustment", b)
+	}
+	if got := level(t, s, "a"); got != 6 {
+		t.Errorf("level of a = %d, want 6", got)
+	}
+	if got := level(t, s, "b"); got != 5 {
+		t.Errorf("level of b = %d, want 5", got)
+	}
+}
+
+func TestCycleCountSkipsSerializedCorrections(t *testing.T) {
+	s := newTestServer(t, nil)
+	ctx := context.Background()
+	_, err := s.CreateProduct(ctx, &pb.CreateProductRequest{
+		Product: &pb.Product{Id: "s", PriceMoney: usd(10), Serialized: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.ReceiveSerials(ctx, &pb.ReceiveSerialsRequest{ProductId: "s", SerialNumbers: []string{"s1", "s2"}})
+	if err != nil {
+		t.Fatalf("ReceiveSerials: %v", err)
+	}
+	count, err := s.StartCount(ctx, &pb.StartCountRequest{Count: &pb.CycleCount{Lines: []*pb.CountLine{{ProductId: "s"}}}})
+	if err != nil {
+		t.Fatalf("StartCount: %v", err)
+	}
+	_, err = s.SubmitCount(ctx, &pb.SubmitCountRequest{CountId: count.Id, Entries: []*pb.CountEntry{{ProductId: "s", CountedQuantity: 1}}})
+	if err != nil {
+		t.Fatalf("SubmitCount: %v", err)
+	}
+	count, err = s.FinalizeCount(ctx, &pb.FinalizeCountRequest{CountId: count.Id})
+	if err != nil {
+		t.Fatalf("FinalizeCount: %v", err)
+	}
+	if line := count.Lines[0]; line.Variance != -1 || line.Adjustment != 0 {
+		t.Errorf("line = %v, want a variance of -1 and no adjustment", line)
+	}
+	if got := level(t, s, "s"); got != 2 {
+		t.Errorf("level = %d, want 2", got)
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	pb "github.com/kingztech2019/proto_grpc/proto_inventory/grpc-inventory-proto"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+)
+
+// Resource types reported in ResourceInfo error details
+const (
+	productResource   = "inventory.Product"
+	orderResource     = "inventory.Order"
+	warehouseResource = "inventory.Warehouse"
+	transferResource  = "inventory.Transfer"
+	lotResource       = "inventory.Lot"
+	serialResource    = "inventory.SerialNumber"
+	countResource     = "inventory.CycleCount"
+	holdResource      = "inventory.Hold"
+)
+
+// withDetails builds a status error carrying the given details, falling back
+// to the bare status if the details cannot be attached.
+func withDetails(code codes.Code, msg string, details ...protoadapt.MessageV1) error {
+	st := status.New(code, msg)
+	if detailed, err := st.WithDetails(details...); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+// notFound reports a missing resource with a ResourceInfo detail
+func notFound(resourceType, name string) error {
+	return withDetails(codes.NotFound, fmt.Sprintf("%s %q not found", resourceType, name),
+		&errdetails.ResourceInfo{ResourceType: resourceType, ResourceName: name, Description: "not found"})
+}
+
+// alreadyExists reports an ID collision with a ResourceInfo detail
+func alreadyExists(resourceType, name string) error {
+	return withDetails(codes.AlreadyExists, fmt.Sprintf("%s %q already exists", resourceType, name),
+		&errdetails.ResourceInfo{ResourceType: resourceType, ResourceName: name, Description: "already exists"})
+}
+
+// invalidArgument reports a bad request field with a BadRequest detail
+func invalidArgument(field, description string) error {
+	return withDetails(codes.InvalidArgument, fmt.Sprintf("invalid %s: %s", field, description),
+		&errdetails.BadRequest{FieldViolations: []*errdetails.BadRequest_FieldViolation{
+			{Field: field, Description: description},
+		}})
+}
+
+// failedPrecondition reports a system state that blocks the request with a
+// PreconditionFailure detail. violationType is a short machine-readable
+// category such as "STOCK".
+func failedPrecondition(violationType, subject, description string) error {
+	return withDetails(codes.FailedPrecondition, description,
+		&errdetails.PreconditionFailure{Violations: []*errdetails.PreconditionFailure_Violation{
+			{Type: violationType, Subject: subject, Description: description},
+		}})
+}
+
+// versionMismatch reports a write made against a stale version with an
+// ErrorInfo detail carrying both versions
+func versionMismatch(resourceType, name string, expected, actual int64) error {
+	return withDetails(codes.Aborted,
+		fmt.Sprintf("%s %q is at version %d, not %d", resourceType, name, actual, expected),
+		&errdetails.ErrorInfo{Reason: "VERSION_MISMATCH", Domain: "inventory", Metadata: map[string]string{
+			"expected_version": strconv.FormatInt(expected, 10),
+			"actual_version":   strconv.FormatInt(actual, 10),
+		}},
+		&errdetails.ResourceInfo{ResourceType: resourceType, ResourceName: name, Description: "version mismatch"})
+}
+
+// storeError converts an error from a store transaction into a gRPC status.
+// Status errors raised inside the transaction pass through unchanged.
+func storeError(err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return status.FromContextError(err).Err()
+	}
+	return status.Errorf(codes.Internal, "storage failure: %v", err)
+}
+
+// The helpers below return err as a gRPC status error, or, when the server
+// runs with -legacy-status, fold it into the response's status/message
+// fields with a nil error the way the service originally reported failures.
+
+func (s *server) productError(err error) (*pb.ProductResponse, error) {
+	if s.legacyStatus {
+		return &pb.ProductResponse{Status: "error", Message: status.Convert(err).Message()}, nil
+	}
+	return nil, err
+}
+
+func (s *server) updateProductError(err error) (*pb.UpdateProductResponse, error) {
+	if s.legacyStatus {
+		return &pb.UpdateProductResponse{Status: "error", Message: status.Convert(err).Message()}, nil
+	}
+	return nil, err
+}
+
+func (s *server) deleteProductError(err error) (*pb.DeleteProductResponse, error) {
+	if s.legacyStatus {
+		return &pb.DeleteProductResponse{Success: false}, nil
+	}
+	return nil, err
+}
+
+func (s *server) orderError(err error) (*pb.OrderResponse, error) {
+	if s.legacyStatus {
+		return &pb.OrderResponse{Status: "error", Message: status.Convert(err).Message()}, nil
+	}
+	return nil, err
+}
+
+func (s *server) updateOrderError(err error) (*pb.UpdateOrderResponse, error) {
+	if s.legacyStatus {
+		return &pb.UpdateOrderResponse{Status: "error", Message: status.Convert(err).Message()}, nil
+	}
+	return nil, err
+}
+
+func (s *server) deleteOrderError(err error) (*pb.DeleteOrderResponse, error) {
+	if s.legacyStatus {
+		return &pb.DeleteOrderResponse{Success: false, Message: status.Convert(err).Message()}, nil
+	}
+	return nil, err
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	pb "github.com/kingztech2019/proto_grpc/proto_inventory/grpc-inventory-proto"
+	"github.com/kingztech2019/proto_grpc/proto_inventory/store"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	defaultEventRetention = 10000
+	watchBatchSize        = 100
+
+	// watchPollInterval bounds how stale a stream can get when events are
+	// committed by another server sharing the same database.
+	watchPollInterval = time.Second
+)
+
+// eventHub wakes WatchInventory streams when new events are committed
+type eventHub struct {
+	mu sync.Mutex
+	ch chan struct{}
+}
+
+func newEventHub() *eventHub {
+	return &eventHub{ch: make(chan struct{})}
+}
+
+// wait returns a channel that is closed by the next notify
+func (h *eventHub) wait() <-chan struct{} {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.ch
+}
+
+func (h *eventHub) notify() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	close(h.ch)
+	h.ch = make(chan struct{})
+}
+
+// update runs fn in a store transaction and wakes event and alert watchers
+// once it has committed
+func (s *server) update(ctx context.Context, fn func(tx store.Tx) error) error {
+	if err := s.store.Update(ctx, fn); err != nil {
+		return err
+	}
+	s.events.notify()
+	return nil
+}
+
+// recordStockEvent appends an InventoryEvent if a product write changes its
+// inventory level, and an alert if it leaves the product low on stock.
+// before is nil when the product is created and after is nil when it is
+// deleted. Events older than the retention window are trimmed in the same
+// transaction.
+func (s *server) recordStockEvent(tx store.Tx, before, after *pb.Product, cause string) error {
+	if err := recordAlert(tx, before, after, cause); err != nil {
+		return err
+	}
+	event := &pb.InventoryEvent{Cause: cause, Time: timestamppb.Now()}
+	switch {
+	case before == nil:
+		event.Type = pb.InventoryEventType_PRODUCT_CREATED
+		event.ProductId = after.Id
+		event.InventoryLevel = after.InventoryLevel
+	case after == nil:
+		event.Type = pb.InventoryEventType_PRODUCT_DELETED
+		event.ProductId = before.Id
+		event.PreviousInventoryLevel = before.InventoryLevel
+	case before.InventoryLevel != after.InventoryLevel:
+		event.Type = pb.InventoryEventType_STOCK_CHANGED
+		event.ProductId = after.Id
+		event.PreviousInventoryLevel = before.InventoryLevel
+		event.InventoryLevel = after.InventoryLevel
+	default:
+		return nil
+	}
+	return s.appendEvent(tx, event)
+}
+
+// appendEvent adds event to the event log, trimming events older than the
+// retention window in the same transaction
+func (s *server) appendEvent(tx store.Tx, event *pb.InventoryEvent) error {
+	if err := tx.Events().Append(event); err != nil {
+		return err
+	}
+	if event.Sequence > uint64(s.eventRetention) {
+		return tx.Events().DeleteBefore(event.Sequence - uint64(s.eventRetention) + 1)
+	}
+	return nil
+}
+
+func resumeToken(seq uint64) string {
+	return strconv.FormatUint(seq, 10)
+}
+
+// WatchInventory streams inventory events as they are committed. A client
+// that reconnects with the resume_token of the last event it saw receives
+// every event after it, as long as they are still within the retention
+// window.
+func (s *server) WatchInventory(req *pb.WatchInventoryRequest, stream grpc.ServerStreamingServer[pb.InventoryEvent]) error {
+	ctx := stream.Context()
+	want := make(map[string]bool, len(req.ProductIds))
+	for _, id := range req.ProductIds {
+		want[id] = true
+	}
+	wantOrders := make(map[string]bool, len(req.OrderIds))
+	for _, id := range req.OrderIds {
+		wantOrders[id] = true
+	}
+
+	var first, last uint64
+	err := s.store.View(ctx, func(tx store.Tx) error {
+		var err error
+		first, last, err = tx.Events().Bounds()
+		return err
+	})
+	if err != nil {
+		return storeError(err)
+	}
+
+	after := last
+	if req.ResumeToken != "" {
+		after, err = strconv.ParseUint(req.ResumeToken, 10, 64)
+		if err != nil {
+			return invalidArgument("resume_token", "malformed token")
+		}
+		if after > last {
+			return invalidArgument("resume_token", "token is ahead of the event log")
+		}
+		if first > 0 && after+1 < first {
+			return status.Errorf(codes.OutOfRange,
+				"events after resume_token %s are no longer retained; the oldest is %d", req.ResumeToken, first)
+		}
+	}
+
+	poll := time.NewTicker(watchPollInterval)
+	defer poll.Stop()
+	for {
+		// Take the wake channel before reading so an event committed
+		// between the read and the wait is not missed.
+		wake := s.events.wait()
+		var events []*pb.InventoryEvent
+		err := s.store.View(ctx, func(tx store.Tx) error {
+			var err error
+			events, err = tx.Events().ListAfter(after, watchBatchSize)
+			return err
+		})
+		if err != nil {
+			return storeError(err)
+		}
+		for _, event := range events {
+			after = event.Sequence
+			if len(want) > 0 && event.ProductId != "" && !want[event.ProductId] ||
+				len(wantOrders) > 0 && !wantOrders[event.OrderId] {
+				continue
+			}
+			event.ResumeToken = resumeToken(event.Sequence)
+			if err := stream.Send(event); err != nil {
+				return err
+			}
+		}
+		if len(events) == watchBatchSize {
+			continue
+		}
+
+		select {
+		case <-wake:
+		case <-poll.C:
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+
+	pb "github.com/kingztech2019/proto_grpc/proto_inventory/grpc-inventory-proto"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+// Fields an update_mask may name. Output-only fields such as the ID,
+// version, status and totals are maintained by the server.
+var (
+	productMaskFields = []string{"name", "price", "price_money", "inventory_level", "reorder_point", "reorder_quantity", "serialized"}
+	orderMaskFields   = []string{"lines", "product_id", "quantity", "warehouse_id"}
+)
+
+// maskPaths validates mask against the fields that may be updated and
+// returns its paths. A nil result means the whole message is replaced,
+// which is what an empty mask or "*" asks for.
+func maskPaths(mask *fieldmaskpb.FieldMask, msg proto.Message, updatable []string) ([]string, error) {
+	paths := mask.GetPaths()
+	if len(paths) == 0 || len(paths) == 1 && paths[0] == "*" {
+		return nil, nil
+	}
+	fields := msg.ProtoReflect().Descriptor().Fields()
+	for _, path := range paths {
+		switch {
+		case path == "*":
+			return nil, invalidArgument("update_mask", `"*" cannot be combined with other paths`)
+		case fields.ByName(protoreflect.Name(path)) == nil:
+			return nil, invalidArgument("update_mask", fmt.Sprintf("unknown field %q", path))
+		case !slices.Contains(updatable, path):
+			return nil, invalidArgument("update_mask", fmt.Sprintf("field %q cannot be updated", path))
+		}
+	}
+	return paths, nil
+}
+
+// applyMask copies the fields named by paths from src into dst. A field
+// that is unset in src is cleared in dst.
+func applyMask(dst, src proto.Message, paths []string) {
+	d, s := dst.ProtoReflect(), proto.Clone(src).ProtoReflect()
+	for _, path := range paths {
+		field := d.Descriptor().Fields().ByName(protoreflect.Name(path))
+		if s.Has(field) {
+			d.Set(field, s.Get(field))
+		} else {
+			d.Clear(field)
+		}
+	}
+}
+
+// mergeOrder applies a masked update to a copy of existing. Naming the
+// single-product fields replaces the lines with one built from them.
+func mergeOrder(existing, update *pb.Order, paths []string) *pb.Order {
+	order := proto.Clone(existing).(*pb.Order)
+	applyMask(order, update, paths)
+	if !slices.Contains(paths, "lines") &&
+		(slices.Contains(paths, "product_id") || slices.Contains(paths, "quantity")) {
+		order.Lines = nil
+	}
+	return order
+}
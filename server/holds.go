@@ -0,0 +1,436 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	pb "github.com/kingztech2019/proto_grpc/proto_inventory/grpc-inventory-proto"
+	"github.com/kingztech2019/proto_grpc/proto_inventory/store"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	defaultHoldTTL = 15 * time.Minute
+
+	// holdExpiryInterval bounds how long a lapsed hold keeps its stock.
+	holdExpiryInterval = 5 * time.Second
+	holdExpiryBatch    = 100
+)
+
+func holdClaim(hold *pb.Hold) stockClaim {
+	return stockClaim{
+		allocations: &hold.LotAllocations,
+		reserved:    pb.StockMovementReason_HOLD_PLACED,
+		released:    pb.StockMovementReason_HOLD_RELEASED,
+		holdID:      hold.Id,
+	}
+}
+
+// holdDeltas sums the hold's quantities per product, negated if release is
+// set, in the form claimStock takes
+func holdDeltas(hold *pb.Hold, release bool) map[string]int32 {
+	deltas := make(map[string]int32, len(hold.Lines))
+	for _, line := range hold.Lines {
+		if release {
+			deltas[line.ProductId] -= line.Quantity
+		} else {
+			deltas[line.ProductId] += line.Quantity
+		}
+	}
+	return deltas
+}
+
+// validateHoldLines checks that lines name each product once with a
+// positive quantity
+func validateHoldLines(lines []*pb.HoldLine) error {
+	if len(lines) == 0 {
+		return invalidArgument("hold.lines", "at least one line is required")
+	}
+	seen := make(map[string]bool, len(lines))
+	for i, line := range lines {
+		name := fmt.Sprintf("hold.lines[%d]", i)
+		switch {
+		case line == nil:
+			return invalidArgument(name, "is required")
+		case line.ProductId == "":
+			return invalidArgument(name+".product_id", "is required")
+		case line.Quantity <= 0:
+			return invalidArgument(name+".quantity", "must be positive")
+		case seen[line.ProductId]:
+			return invalidArgument(name+".product_id", fmt.Sprintf("product %q is listed twice", line.ProductId))
+		}
+		seen[line.ProductId] = true
+	}
+	return nil
+}
+
+// holdDuration returns how long a hold requested with ttl lasts
+func (s *server) holdDuration(ttl *durationpb.Duration) (time.Duration, error) {
+	if ttl == nil {
+		return s.holdTTL, nil
+	}
+	if err := ttl.CheckValid(); err != nil {
+		return 0, invalidArgument("ttl", err.Error())
+	}
+	d := ttl.AsDuration()
+	if d <= 0 {
+		return 0, invalidArgument("ttl", "must be positive")
+	}
+	return d, nil
+}
+
+// getHold loads a hold, reporting a missing one as NotFound
+func getHold(tx store.Tx, id string) (*pb.Hold, error) {
+	hold, err := tx.Holds().Get(id)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, notFound(holdResource, id)
+	}
+	return hold, err
+}
+
+// activeHoldOn returns an active hold that holds productID, or any active
+// hold at warehouseID, or nil if there is none. Empty filters are ignored.
+func activeHoldOn(tx store.Tx, productID, warehouseID string) (*pb.Hold, error) {
+	holds, err := tx.Holds().ListActive()
+	if err != nil {
+		return nil, err
+	}
+	for _, hold := range holds {
+		if warehouseID != "" && hold.WarehouseId == warehouseID ||
+			productID != "" && slices.ContainsFunc(hold.Lines, func(l *pb.HoldLine) bool { return l.ProductId == productID }) {
+			return hold, nil
+		}
+	}
+	return nil, nil
+}
+
+// isLapsed reports whether an active hold is past its expiry and waiting
+// to be released. A hold without an expiry never lapses.
+func isLapsed(hold *pb.Hold, now time.Time) bool {
+	return hold.Status == pb.HoldStatus_HOLD_STATUS_ACTIVE && hold.ExpireTime != nil &&
+		!hold.ExpireTime.AsTime().After(now)
+}
+
+// isActive fails unless the hold is active, and with unexpired set, also
+// unless it has yet to lapse
+func isActive(hold *pb.Hold, unexpired bool) error {
+	switch {
+	case hold.Status != pb.HoldStatus_HOLD_STATUS_ACTIVE:
+		return failedPrecondition("HOLD_STATUS", hold.Id, fmt.Sprintf("hold %q is %s", hold.Id, hold.Status))
+	case unexpired && isLapsed(hold, time.Now()):
+		return failedPrecondition("HOLD_STATUS", hold.Id, fmt.Sprintf(
+			"hold %q expired at %s", hold.Id, hold.ExpireTime.AsTime().Format(time.RFC3339)))
+	}
+	return nil
+}
+
+// releaseHold returns an active hold's stock and closes it with status to
+func (s *server) releaseHold(tx store.Tx, hold *pb.Hold, to pb.HoldStatus, cause string) error {
+	if err := s.claimStock(tx, holdClaim(hold), hold.WarehouseId, holdDeltas(hold, true), cause); err != nil {
+		return err
+	}
+	hold.Status = to
+	hold.Version++
+	hold.UpdateTime = timestamppb.Now()
+	return tx.Holds().Update(hold)
+}
+
+// CreateHold sets stock aside for a cart at one warehouse. Either every
+// line is held or the hold is rejected.
+func (s *server) CreateHold(ctx context.Context, req *pb.CreateHoldRequest) (*pb.Hold, error) {
+	if req.Hold == nil {
+		return nil, invalidArgument("hold", "is required")
+	}
+	if err := validateHoldLines(req.Hold.Lines); err != nil {
+		return nil, err
+	}
+	ttl, err := s.holdDuration(req.Ttl)
+	if err != nil {
+		return nil, err
+	}
+	key, err := idempotencyKey(ctx, req.IdempotencyKey)
+	if err != nil {
+		return nil, err
+	}
+	h := proto.Clone(req.Hold).(*pb.Hold)
+	hold := &pb.Hold{Id: h.Id, WarehouseId: h.WarehouseId, Lines: h.Lines}
+	if hold.Id == "" {
+		if hold.Id, err = newID(); err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to generate hold id: %v", err)
+		}
+	}
+
+	res := &pb.Hold{}
+	err = s.mutate(ctx, key, req, res, func(tx store.Tx) error {
+		deltas := holdDeltas(hold, false)
+		if hold.WarehouseId == "" {
+			var err error
+			if hold.WarehouseId, err = s.fulfillmentWarehouse(tx, deltas); err != nil {
+				return err
+			}
+		} else if _, err := getWarehouse(tx, hold.WarehouseId); err != nil {
+			return err
+		}
+		hold.LotAllocations = nil
+		if err := s.claimStock(tx, holdClaim(hold), hold.WarehouseId, deltas, "CreateHold"); err != nil {
+			return err
+		}
+		now := time.Now()
+		hold.Status = pb.HoldStatus_HOLD_STATUS_ACTIVE
+		hold.Version = 1
+		hold.CreateTime = timestamppb.New(now)
+		hold.UpdateTime = hold.CreateTime
+		hold.ExpireTime = timestamppb.New(now.Add(ttl))
+		err := tx.Holds().Create(hold)
+		if errors.Is(err, store.ErrAlreadyExists) {
+			return alreadyExists(holdResource, hold.Id)
+		}
+		if err != nil {
+			return err
+		}
+		proto.Reset(res)
+		proto.Merge(res, hold)
+		return nil
+	})
+	if err != nil {
+		return nil, storeError(err)
+	}
+	return res, nil
+}
+
+// GetHold fetches a hold by its ID
+func (s *server) GetHold(ctx context.Context, req *pb.GetHoldRequest) (*pb.Hold, error) {
+	var hold *pb.Hold
+	err := s.store.View(ctx, func(tx store.Tx) error {
+		var err error
+		hold, err = getHold(tx, req.HoldId)
+		return err
+	})
+	if err != nil {
+		return nil, storeError(err)
+	}
+	return hold, nil
+}
+
+// ExtendHold moves an unexpired hold's expiry to ttl from now
+func (s *server) ExtendHold(ctx context.Context, req *pb.ExtendHoldRequest) (*pb.Hold, error) {
+	if req.HoldId == "" {
+		return nil, invalidArgument("hold_id", "is required")
+	}
+	ttl, err := s.holdDuration(req.Ttl)
+	if err != nil {
+		return nil, err
+	}
+	key, err := idempotencyKey(ctx, req.IdempotencyKey)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &pb.Hold{}
+	err = s.mutate(ctx, key, req, res, func(tx store.Tx) error {
+		hold, err := getHold(tx, req.HoldId)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(holdResource, hold.Id, req.ExpectedVersion, hold.Version); err != nil {
+			return err
+		}
+		if err := isActive(hold, true); err != nil {
+			return err
+		}
+		now := time.Now()
+		hold.ExpireTime = timestamppb.New(now.Add(ttl))
+		hold.Version++
+		hold.UpdateTime = timestamppb.New(now)
+		if err := tx.Holds().Update(hold); err != nil {
+			return err
+		}
+		proto.Reset(res)
+		proto.Merge(res, hold)
+		return nil
+	})
+	if err != nil {
+		return nil, storeError(err)
+	}
+	return res, nil
+}
+
+// ReleaseHold returns an active hold's stock
+func (s *server) ReleaseHold(ctx context.Context, req *pb.ReleaseHoldRequest) (*pb.Hold, error) {
+	if req.HoldId == "" {
+		return nil, invalidArgument("hold_id", "is required")
+	}
+	key, err := idempotencyKey(ctx, req.IdempotencyKey)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &pb.Hold{}
+	err = s.mutate(ctx, key, req, res, func(tx store.Tx) error {
+		hold, err := getHold(tx, req.HoldId)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(holdResource, hold.Id, req.ExpectedVersion, hold.Version); err != nil {
+			return err
+		}
+		if err := isActive(hold, false); err != nil {
+			return err
+		}
+		if err := s.releaseHold(tx, hold, pb.HoldStatus_HOLD_STATUS_RELEASED, "ReleaseHold"); err != nil {
+			return err
+		}
+		proto.Reset(res)
+		proto.Merge(res, hold)
+		return nil
+	})
+	if err != nil {
+		return nil, storeError(err)
+	}
+	return res, nil
+}
+
+// ConvertHoldToOrder turns an unexpired hold into a pending order that
+// keeps the held stock as its reservation
+func (s *server) ConvertHoldToOrder(ctx context.Context, req *pb.ConvertHoldToOrderRequest) (*pb.OrderResponse, error) {
+	if req.HoldId == "" {
+		return s.orderError(invalidArgument("hold_id", "is required"))
+	}
+	key, err := idempotencyKey(ctx, req.IdempotencyKey)
+	if err != nil {
+		return s.orderError(err)
+	}
+	orderID := req.OrderId
+	if orderID == "" {
+		if orderID, err = newID(); err != nil {
+			return s.orderError(status.Errorf(codes.Internal, "failed to generate order id: %v", err))
+		}
+	}
+
+	res := &pb.OrderResponse{}
+	err = s.mutate(ctx, key, req, res, func(tx store.Tx) error {
+		hold, err := getHold(tx, req.HoldId)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(holdResource, hold.Id, req.ExpectedVersion, hold.Version); err != nil {
+			return err
+		}
+		if err := isActive(hold, true); err != nil {
+			return err
+		}
+		order := &pb.Order{Id: orderID, WarehouseId: hold.WarehouseId, LotAllocations: hold.LotAllocations}
+		for _, line := range hold.Lines {
+			order.Lines = append(order.Lines, &pb.OrderLine{ProductId: line.ProductId, Quantity: line.Quantity})
+		}
+		normalizeLines(order)
+		if err := s.priceOrder(tx, order, nil); err != nil {
+			return err
+		}
+		order.OrderDate = timestamppb.Now()
+		order.Version = 1
+		order.Status = pb.OrderStatus_ORDER_STATUS_PENDING
+		err = tx.Orders().Create(order)
+		if errors.Is(err, store.ErrAlreadyExists) {
+			return alreadyExists(orderResource, order.Id)
+		}
+		if err != nil {
+			return err
+		}
+
+		hold.Status = pb.HoldStatus_HOLD_STATUS_CONVERTED
+		hold.OrderId = order.Id
+		hold.LotAllocations = nil
+		hold.Version++
+		hold.UpdateTime = order.OrderDate
+		if err := tx.Holds().Update(hold); err != nil {
+			return err
+		}
+		err = s.recordHandover(tx, hold.WarehouseId, holdDeltas(hold, false), &pb.StockMovement{
+			Reason:  pb.StockMovementReason_HOLD_CONVERTED,
+			OrderId: order.Id,
+			HoldId:  hold.Id,
+			Cause:   "ConvertHoldToOrder",
+		})
+		if err != nil {
+			return err
+		}
+		res.Order, res.Status, res.Message = order, "success", "Order created"
+		return nil
+	})
+	if err != nil {
+		return s.orderError(storeError(err))
+	}
+	return res, nil
+}
+
+// expireHolds releases holds as they lapse until ctx is done
+func (s *server) expireHolds(ctx context.Context) {
+	ticker := time.NewTicker(holdExpiryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+		for {
+			n, err := s.expireLapsedHolds(ctx)
+			if err != nil && ctx.Err() == nil {
+				log.Printf("failed to expire holds: %v", err)
+			}
+			if err != nil || n < holdExpiryBatch {
+				break
+			}
+		}
+	}
+}
+
+// expireLapsedHolds releases a batch of lapsed holds, each in its own
+// transaction, recording a HOLD_EXPIRED event for each. It returns how many
+// it found.
+func (s *server) expireLapsedHolds(ctx context.Context) (int, error) {
+	var holds []*pb.Hold
+	err := s.store.View(ctx, func(tx store.Tx) error {
+		var err error
+		holds, err = tx.Holds().ListExpired(time.Now(), holdExpiryBatch)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	for _, lapsed := range holds {
+		err := s.update(ctx, func(tx store.Tx) error {
+			hold, err := tx.Holds().Get(lapsed.Id)
+			if err != nil {
+				return err
+			}
+			// The hold may have been released, converted or extended since
+			// it was listed.
+			if !isLapsed(hold, time.Now()) {
+				return nil
+			}
+			if err := s.releaseHold(tx, hold, pb.HoldStatus_HOLD_STATUS_EXPIRED, "ExpireHolds"); err != nil {
+				return err
+			}
+			return s.appendEvent(tx, &pb.InventoryEvent{
+				Type:   pb.InventoryEventType_HOLD_EXPIRED,
+				HoldId: hold.Id,
+				Cause:  "ExpireHolds",
+				Time:   timestamppb.Now(),
+			})
+		})
+		if err != nil {
+			return 0, fmt.Errorf("hold %q: %w", lapsed.Id, err)
+		}
+	}
+	return len(holds), nil
+}
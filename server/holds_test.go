@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "github.com/kingztech2019/proto_grpc/proto_inventory/grpc-inventory-proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// createHold holds lines of a product for ttl
+func createHold(t *testing.T, s *server, productID string, quantity int32, ttl time.Duration) *pb.Hold {
+	t.Helper()
+	hold, err := s.CreateHold(context.Background(), &pb.CreateHoldRequest{
+		Hold: &pb.Hold{Lines: []*pb.HoldLine{{ProductId: productID, Quantity: quantity}}},
+		Ttl:  durationpb.New(ttl),
+	})
+	if err != nil {
+		t.Fatalf("CreateHold: %v", err)
+	}
+	return hold
+}
+
+func TestHoldExpiry(t *testing.T) {
+	s := newTestServer(t, nil)
+	ctx := context.Background()
+	createProduct(t, s, "a", 10)
+
+	lapsing := createHold(t, s, "a", 3, time.Millisecond)
+	lasting := createHold(t, s, "a", 2, time.Hour)
+	if got := level(t, s, "a"); got != 5 {
+		t.Fatalf("level with two holds = %d, want 5", got)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	_, err := s.ConvertHoldToOrder(ctx, &pb.ConvertHoldToOrderRequest{HoldId: lapsing.Id})
+	wantCode(t, err, codes.FailedPrecondition)
+	n, err := s.expireLapsedHolds(ctx)
+	if err != nil {
+		t.Fatalf("expireLapsedHolds: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("expired %d holds, want 1", n)
+	}
+	hold, err := s.GetHold(ctx, &pb.GetHoldRequest{HoldId: lapsing.Id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hold.Status != pb.HoldStatus_HOLD_STATUS_EXPIRED {
+		t.Errorf("lapsed hold is %s, want EXPIRED", hold.Status)
+	}
+	if got := level(t, s, "a"); got != 8 {
+		t.Errorf("level after expiry = %d, want 8", got)
+	}
+	if n, err := s.expireLapsedHolds(ctx); err != nil || n != 0 {
+		t.Errorf("second expiry pass = %d, %v, want 0, nil", n, err)
+	}
+
+	// A converted hold's stock stays reserved by the order.
+	res, err := s.ConvertHoldToOrder(ctx, &pb.ConvertHoldToOrderRequest{HoldId: lasting.Id})
+	if err != nil {
+		t.Fatalf("ConvertHoldToOrder: %v", err)
+	}
+	if got := level(t, s, "a"); got != 8 {
+		t.Errorf("level after conversion = %d, want 8", got)
+	}
+	advance(t, res.Order.Id, s.CancelOrder)
+	if got := level(t, s, "a"); got != 10 {
+		t.Errorf("level after cancelling the converted order = %d, want 10", got)
+	}
+}
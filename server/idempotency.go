@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"log"
+	"time"
+
+	pb "github.com/kingztech2019/proto_grpc/proto_inventory/grpc-inventory-proto"
+	"github.com/kingztech2019/proto_grpc/proto_inventory/store"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	defaultIdempotencyWindow = 24 * time.Hour
+	idempotencyKeyHeader     = "idempotency-key"
+	maxIdempotencyKeyLength  = 255
+	idempotencySweepInterval = time.Minute
+)
+
+// errKeyClaimed reports that a concurrent request stored a record for the
+// same idempotency key first
+var errKeyClaimed = errors.New("idempotency key claimed by a concurrent request")
+
+// idempotencyKey returns the key sent in the request field or the
+// idempotency-key metadata header, or "" if there is none
+func idempotencyKey(ctx context.Context, field string) (string, error) {
+	key := field
+	for _, v := range metadata.ValueFromIncomingContext(ctx, idempotencyKeyHeader) {
+		if key != "" && v != key {
+			return "", invalidArgument("idempotency_key", "does not match the idempotency-key header")
+		}
+		key = v
+	}
+	if len(key) > maxIdempotencyKeyLength {
+		return "", invalidArgument("idempotency_key", "must be at most 255 bytes")
+	}
+	return key, nil
+}
+
+// requestHash fingerprints a request so a reused key can be told apart
+// from a retry. The key itself is left out of the hash.
+func requestHash(method string, req proto.Message) ([]byte, error) {
+	req = proto.Clone(req)
+	if field := req.ProtoReflect().Descriptor().Fields().ByName("idempotency_key"); field != nil {
+		req.ProtoReflect().Clear(field)
+	}
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	h := sha256.New()
+	h.Write([]byte(method))
+	h.Write([]byte{0})
+	h.Write(data)
+	return h.Sum(nil), nil
+}
+
+// mutate runs fn in an update transaction that fills in res. With an
+// idempotency key, the response is stored in the same transaction, and a
+// retry within the idempotency window gets it back without fn running
+// again. Failed requests are not stored, so retrying them runs fn again.
+func (s *server) mutate(ctx context.Context, key string, req, res proto.Message, fn func(tx store.Tx) error) error {
+	if key == "" {
+		return s.update(ctx, fn)
+	}
+	method, _ := grpc.Method(ctx)
+	hash, err := requestHash(method, req)
+	if err != nil {
+		return err
+	}
+
+	attempt := func(tx store.Tx) error {
+		now := time.Now()
+		record, err := tx.Idempotency().Get(key)
+		exists := err == nil
+		if err != nil && !errors.Is(err, store.ErrNotFound) {
+			return err
+		}
+		if exists && now.Before(record.ExpireTime.AsTime()) {
+			if record.Method != method || !bytes.Equal(record.RequestHash, hash) {
+				return invalidArgument("idempotency_key", "was already used for a different request")
+			}
+			return record.Response.UnmarshalTo(res)
+		}
+
+		if err := fn(tx); err != nil {
+			return err
+		}
+		response, err := anypb.New(res)
+		if err != nil {
+			return err
+		}
+		record = &pb.IdempotencyRecord{
+			Key:         key,
+			Method:      method,
+			RequestHash: hash,
+			Response:    response,
+			CreateTime:  timestamppb.New(now),
+			ExpireTime:  timestamppb.New(now.Add(s.idempotencyWindow)),
+		}
+		if exists {
+			return tx.Idempotency().Update(record)
+		}
+		err = tx.Idempotency().Create(record)
+		if errors.Is(err, store.ErrAlreadyExists) {
+			return errKeyClaimed
+		}
+		return err
+	}
+
+	// A request racing another with the same key loses at Create; running
+	// it once more replays the winner's response.
+	err = s.update(ctx, attempt)
+	if errors.Is(err, errKeyClaimed) {
+		err = s.update(ctx, attempt)
+	}
+	return err
+}
+
+// sweepIdempotencyKeys deletes expired idempotency records until ctx is done
+func (s *server) sweepIdempotencyKeys(ctx context.Context) {
+	ticker := time.NewTicker(idempotencySweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+		err := s.store.Update(ctx, func(tx store.Tx) error {
+			_, err := tx.Idempotency().DeleteExpired(time.Now())
+			return err
+		})
+		if err != nil && ctx.Err() == nil {
+			log.Printf("failed to delete expired idempotency keys: %v", err)
+		}
+	}
+}
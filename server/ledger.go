@@ -0,0 +1,298 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	pb "github.com/kingztech2019/proto_grpc/proto_inventory/grpc-inventory-proto"
+	"github.com/kingztech2019/proto_grpc/proto_inventory/store"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// movementCursor is the sort key stored in a ListStockMovements page token.
+type movementCursor struct {
+	Sequence uint64 `json:"seq"`
+}
+
+// appendMovement records m in the ledger. The caller is responsible for
+// applying the same change to the product and its stock levels.
+func appendMovement(tx store.Tx, m *pb.StockMovement) error {
+	m.Time = timestamppb.Now()
+	return tx.Movements().Append(m)
+}
+
+// recordHandover notes in the ledger of each product in deltas that its
+// units at warehouseID changed hands without moving: a hold's units became
+// an order's reservation, or an order's reservation left with its shipment.
+// The movements carry no delta. Products are visited in ID order and
+// deleted ones are skipped.
+func (s *server) recordHandover(tx store.Tx, warehouseID string, deltas map[string]int32, m *pb.StockMovement) error {
+	ids := make([]string, 0, len(deltas))
+	for id, delta := range deltas {
+		if delta != 0 {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+
+	for _, productID := range ids {
+		product, err := tx.Products().Get(productID)
+		if errors.Is(err, store.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		normalizeProductPrice(product, s.currency)
+		after, err := s.stockAt(tx, product, warehouseID)
+		if err != nil {
+			return err
+		}
+		movement := proto.Clone(m).(*pb.StockMovement)
+		movement.ProductId, movement.WarehouseId, movement.QuantityAfter = productID, warehouseID, after
+		if err := appendMovement(tx, movement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// moveStock applies m to the product's stock at m.warehouse_id and to its
+// inventory_level, and records it in the ledger and the event log. The
+// warehouse cannot go below zero, and a removal that names no lot trims the
+// lots to fit what is left. New stock goes to waiting backorders first. It
+// returns the updated product.
+func (s *server) moveStock(tx store.Tx, product *pb.Product, m *pb.StockMovement) (*pb.Product, error) {
+	available, err := s.stockAt(tx, product, m.WarehouseId)
+	if err != nil {
+		return nil, err
+	}
+	after := available + m.Delta
+	if after < 0 {
+		return nil, failedPrecondition("STOCK", product.Id, fmt.Sprintf(
+			"insufficient stock for product %q at warehouse %q: requested %d, available %d",
+			product.Id, m.WarehouseId, -m.Delta, available))
+	}
+	if m.WarehouseId != s.defaultWarehouse {
+		level := &pb.StockLevel{ProductId: product.Id, WarehouseId: m.WarehouseId, Quantity: after}
+		if err := tx.Stock().Put(level); err != nil {
+			return nil, err
+		}
+	}
+	if m.Delta < 0 && m.LotId == "" {
+		if err := trimLots(tx, product.Id, m.WarehouseId, after); err != nil {
+			return nil, err
+		}
+	}
+	updated := proto.Clone(product).(*pb.Product)
+	updated.InventoryLevel += m.Delta
+	updated.Version++
+	if err := tx.Products().Update(updated); err != nil {
+		return nil, err
+	}
+	m.ProductId, m.QuantityAfter = product.Id, after
+	if err := appendMovement(tx, m); err != nil {
+		return nil, err
+	}
+	if err := s.recordStockEvent(tx, product, updated, m.Cause); err != nil {
+		return nil, err
+	}
+	if m.Delta < 0 || !backorderSources[m.Reason] {
+		return updated, nil
+	}
+	if err := s.fillBackorders(tx, product.Id, m.WarehouseId, m.Cause); err != nil {
+		return nil, err
+	}
+	return s.getProduct(tx, product.Id)
+}
+
+// reconcileLedger checks every product's stock against the sum of its
+// movements. A product with no movements at all predates the ledger and is
+// given an opening balance for the stock it holds. Any other mismatch means
+// stock was changed outside the ledger; it is logged for investigation and
+// the ledger is left as it is rather than rewritten to hide the drift.
+func (s *server) reconcileLedger(ctx context.Context) error {
+	return s.store.Update(ctx, func(tx store.Tx) error {
+		products, err := tx.Products().List()
+		if err != nil {
+			return err
+		}
+		for _, product := range products {
+			levels, err := s.stockLevels(tx, product)
+			if err != nil {
+				return err
+			}
+			totals, err := tx.Movements().Totals(product.Id)
+			if err != nil {
+				return err
+			}
+			opening := len(totals) == 0
+			// Warehouses in the ledger that no longer hold stock should
+			// reconcile to zero.
+			for warehouseID := range totals {
+				if !slices.ContainsFunc(levels, func(l *pb.StockLevel) bool { return l.WarehouseId == warehouseID }) {
+					levels = append(levels, &pb.StockLevel{ProductId: product.Id, WarehouseId: warehouseID})
+				}
+			}
+			slices.SortFunc(levels[1:], func(a, b *pb.StockLevel) int { return strings.Compare(a.WarehouseId, b.WarehouseId) })
+			for _, level := range levels {
+				delta := level.Quantity - totals[level.WarehouseId]
+				if delta == 0 {
+					continue
+				}
+				if !opening {
+					log.Printf("STOCK LEDGER DRIFT: product %q holds %d units at warehouse %q but its movements sum to %d",
+						product.Id, level.Quantity, level.WarehouseId, totals[level.WarehouseId])
+					continue
+				}
+				err := appendMovement(tx, &pb.StockMovement{
+					ProductId:     product.Id,
+					WarehouseId:   level.WarehouseId,
+					Delta:         delta,
+					Reason:        pb.StockMovementReason_COUNT_CORRECTION,
+					QuantityAfter: level.Quantity,
+					Note:          "opening balance",
+				})
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// adjustableReasons are the reasons AdjustStock accepts and the sign their
+// delta must have; 0 allows either.
+var adjustableReasons = map[pb.StockMovementReason]int{
+	pb.StockMovementReason_RECEIVED:         1,
+	pb.StockMovementReason_DAMAGED:          -1,
+	pb.StockMovementReason_SHRINKAGE:        -1,
+	pb.StockMovementReason_COUNT_CORRECTION: 0,
+}
+
+// AdjustStock adds or removes stock at a warehouse and records why
+func (s *server) AdjustStock(ctx context.Context, req *pb.AdjustStockRequest) (*pb.AdjustStockResponse, error) {
+	sign, ok := adjustableReasons[req.Reason]
+	switch {
+	case req.ProductId == "":
+		return nil, invalidArgument("product_id", "is required")
+	case !ok:
+		return nil, invalidArgument("reason", "must be RECEIVED, DAMAGED, SHRINKAGE or COUNT_CORRECTION")
+	case req.Delta == 0:
+		return nil, invalidArgument("delta", "must not be zero")
+	case sign > 0 && req.Delta < 0:
+		return nil, invalidArgument("delta", fmt.Sprintf("must be positive for %s", req.Reason))
+	case sign < 0 && req.Delta > 0:
+		return nil, invalidArgument("delta", fmt.Sprintf("must be negative for %s", req.Reason))
+	case req.UnitCost != nil && req.Reason != pb.StockMovementReason_RECEIVED:
+		return nil, invalidArgument("unit_cost", "is only accepted with RECEIVED")
+	case len(req.SerialNumbers) > 0 && req.Delta > 0:
+		return nil, invalidArgument("serial_numbers", "are only accepted when removing stock")
+	}
+	if err := validateSerialNumbers("serial_numbers", req.SerialNumbers); err != nil {
+		return nil, err
+	}
+	key, err := idempotencyKey(ctx, req.IdempotencyKey)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &pb.AdjustStockResponse{}
+	err = s.mutate(ctx, key, req, res, func(tx store.Tx) error {
+		product, err := s.getProduct(tx, req.ProductId)
+		if err != nil {
+			return err
+		}
+		if err := checkUnitCost("unit_cost", req.UnitCost, product); err != nil {
+			return err
+		}
+		if product.Serialized && req.Delta > 0 {
+			return failedPrecondition("SERIALIZED", product.Id, fmt.Sprintf(
+				"stock of serialized product %q is added with ReceiveSerials", product.Id))
+		}
+		if err := checkSerialCount(product, req.SerialNumbers, -req.Delta); err != nil {
+			return err
+		}
+		warehouseID := req.WarehouseId
+		if req.LotId != "" {
+			if warehouseID, err = adjustLot(tx, req); err != nil {
+				return err
+			}
+		}
+		if warehouseID == "" {
+			warehouseID = s.defaultWarehouse
+		}
+		if _, err := getWarehouse(tx, warehouseID); err != nil {
+			return err
+		}
+		if err := writeOffSerials(tx, product.Id, req.SerialNumbers, warehouseID, "AdjustStock"); err != nil {
+			return err
+		}
+		movement := &pb.StockMovement{
+			WarehouseId: warehouseID,
+			Delta:       req.Delta,
+			Reason:      req.Reason,
+			Note:        req.Note,
+			LotId:       req.LotId,
+			UnitCost:    req.UnitCost,
+			Cause:       "AdjustStock",
+		}
+		res.Product, err = s.moveStock(tx, product, movement)
+		res.Movement = movement
+		return err
+	})
+	if err != nil {
+		return nil, storeError(err)
+	}
+	return res, nil
+}
+
+// ListStockMovements returns a page of ledger entries matching the request
+// filters, oldest first
+func (s *server) ListStockMovements(ctx context.Context, req *pb.ListStockMovementsRequest) (*pb.ListStockMovementsResponse, error) {
+	size, err := pageSize(req.PageSize)
+	if err != nil {
+		return nil, invalidArgument("page_size", err.Error())
+	}
+	q := store.MovementQuery{ProductID: req.ProductId, WarehouseID: req.WarehouseId, Reason: req.Reason}
+	query := queryFingerprint(q.ProductID, q.WarehouseID, q.Reason)
+
+	if req.PageToken != "" {
+		var cursor movementCursor
+		if err := decodePageToken(req.PageToken, query, &cursor); err != nil {
+			return nil, invalidArgument("page_token", err.Error())
+		}
+		q.AfterSequence = cursor.Sequence
+	}
+	// One extra movement tells whether another page follows.
+	q.Limit = size + 1
+
+	var movements []*pb.StockMovement
+	err = s.store.View(ctx, func(tx store.Tx) error {
+		var err error
+		movements, err = tx.Movements().Query(q)
+		return err
+	})
+	if err != nil {
+		return nil, storeError(err)
+	}
+
+	res := &pb.ListStockMovementsResponse{}
+	if len(movements) > size {
+		movements = movements[:size]
+		res.NextPageToken, err = encodePageToken(query, movementCursor{Sequence: movements[size-1].Sequence})
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+	}
+	res.Movements = movements
+	return res, nil
+}
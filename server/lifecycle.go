@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	pb "github.com/kingztech2019/proto_grpc/proto_inventory/grpc-inventory-proto"
+	"github.com/kingztech2019/proto_grpc/proto_inventory/store"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// orderTransitions lists the statuses each order status may move to
+var orderTransitions = map[pb.OrderStatus][]pb.OrderStatus{
+	pb.OrderStatus_ORDER_STATUS_PENDING:   {pb.OrderStatus_ORDER_STATUS_CONFIRMED, pb.OrderStatus_ORDER_STATUS_CANCELLED},
+	pb.OrderStatus_ORDER_STATUS_CONFIRMED: {pb.OrderStatus_ORDER_STATUS_PICKING, pb.OrderStatus_ORDER_STATUS_CANCELLED},
+	pb.OrderStatus_ORDER_STATUS_PICKING:   {pb.OrderStatus_ORDER_STATUS_SHIPPED, pb.OrderStatus_ORDER_STATUS_CANCELLED},
+	pb.OrderStatus_ORDER_STATUS_SHIPPED:   {pb.OrderStatus_ORDER_STATUS_DELIVERED, pb.OrderStatus_ORDER_STATUS_RETURNED},
+	pb.OrderStatus_ORDER_STATUS_DELIVERED: {pb.OrderStatus_ORDER_STATUS_RETURNED},
+}
+
+// orderStatus returns the order's status, treating orders stored before
+// statuses existed as pending
+func orderStatus(order *pb.Order) pb.OrderStatus {
+	if order.Status == pb.OrderStatus_ORDER_STATUS_UNSPECIFIED {
+		return pb.OrderStatus_ORDER_STATUS_PENDING
+	}
+	return order.Status
+}
+
+// holdsStock reports whether the order's quantity is still reserved from
+// the product: once shipped the stock is gone, and cancelled or returned
+// orders have given it back.
+func holdsStock(order *pb.Order) bool {
+	switch orderStatus(order) {
+	case pb.OrderStatus_ORDER_STATUS_PENDING,
+		pb.OrderStatus_ORDER_STATUS_CONFIRMED,
+		pb.OrderStatus_ORDER_STATUS_PICKING:
+		return true
+	}
+	return false
+}
+
+// isEditable reports whether the order's contents may still be changed
+// through UpdateOrder
+func isEditable(order *pb.Order) bool {
+	switch orderStatus(order) {
+	case pb.OrderStatus_ORDER_STATUS_PENDING, pb.OrderStatus_ORDER_STATUS_CONFIRMED:
+		return true
+	}
+	return false
+}
+
+// setOrderStatus moves order to status, appending to its history
+func setOrderStatus(order *pb.Order, to pb.OrderStatus, actor, reason string) {
+	order.StatusHistory = append(order.StatusHistory, &pb.OrderStatusChange{
+		From:      order.Status,
+		To:        to,
+		ChangedBy: actor,
+		ChangedAt: timestamppb.Now(),
+		Reason:    reason,
+	})
+	order.Status = to
+}
+
+// transitionOrder applies one lifecycle step, rejecting moves the state
+// machine does not allow. Cancelling returns the reserved stock and
+// returning a shipped order restocks it; the order's serial numbers follow
+// its stock. Shipping is noted in the ledger as ORDER_SHIPPED.
+func (s *server) transitionOrder(ctx context.Context, req *pb.OrderTransitionRequest, to pb.OrderStatus, cause string) (*pb.OrderResponse, error) {
+	if req.OrderId == "" {
+		return s.orderError(invalidArgument("order_id", "is required"))
+	}
+	if req.Actor == "" {
+		return s.orderError(invalidArgument("actor", "is required"))
+	}
+
+	var order *pb.Order
+	err := s.update(ctx, func(tx store.Tx) error {
+		var err error
+		order, err = s.getOrder(tx, req.OrderId)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(orderResource, order.Id, req.ExpectedVersion, order.Version); err != nil {
+			return err
+		}
+		from := orderStatus(order)
+		allowed := false
+		for _, next := range orderTransitions[from] {
+			allowed = allowed || next == to
+		}
+		if !allowed {
+			return failedPrecondition("ORDER_STATUS", order.Id,
+				fmt.Sprintf("order %q cannot move from %s to %s", order.Id, from, to))
+		}
+		if n := backordered(order); n > 0 && to == pb.OrderStatus_ORDER_STATUS_SHIPPED {
+			return failedPrecondition("BACKORDERED", order.Id,
+				fmt.Sprintf("order %q is still waiting for %d backordered units", order.Id, n))
+		}
+
+		// Stock only moves on cancellation and return: shipping consumes
+		// the reservation made when the order was created.
+		restock := to == pb.OrderStatus_ORDER_STATUS_RETURNED ||
+			to == pb.OrderStatus_ORDER_STATUS_CANCELLED && holdsStock(order)
+		if restock {
+			if err := s.reserveStock(tx, order, order.WarehouseId, lineDeltas(order, true), cause); err != nil {
+				return err
+			}
+		}
+		if to == pb.OrderStatus_ORDER_STATUS_SHIPPED {
+			err := s.recordHandover(tx, order.WarehouseId, lineDeltas(order, false), &pb.StockMovement{
+				Reason:  pb.StockMovementReason_ORDER_SHIPPED,
+				OrderId: order.Id,
+				Cause:   cause,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		if err := s.transitionSerials(tx, order, to, cause); err != nil {
+			return err
+		}
+		order.Status = from
+		order.Version++
+		setOrderStatus(order, to, req.Actor, req.Reason)
+		return tx.Orders().Update(order)
+	})
+	if err != nil {
+		return s.orderError(storeError(err))
+	}
+	return &pb.OrderResponse{Order: order, Status: "success", Message: "Order " + to.String()}, nil
+}
+
+// ConfirmOrder moves a pending order to confirmed
+func (s *server) ConfirmOrder(ctx context.Context, req *pb.OrderTransitionRequest) (*pb.OrderResponse, error) {
+	return s.transitionOrder(ctx, req, pb.OrderStatus_ORDER_STATUS_CONFIRMED, "ConfirmOrder")
+}
+
+// StartPicking moves a confirmed order to picking
+func (s *server) StartPicking(ctx context.Context, req *pb.OrderTransitionRequest) (*pb.OrderResponse, error) {
+	return s.transitionOrder(ctx, req, pb.OrderStatus_ORDER_STATUS_PICKING, "StartPicking")
+}
+
+// ShipOrder moves a picked order to shipped
+func (s *server) ShipOrder(ctx context.Context, req *pb.OrderTransitionRequest) (*pb.OrderResponse, error) {
+	return s.transitionOrder(ctx, req, pb.OrderStatus_ORDER_STATUS_SHIPPED, "ShipOrder")
+}
+
+// DeliverOrder moves a shipped order to delivered
+func (s *server) DeliverOrder(ctx context.Context, req *pb.OrderTransitionRequest) (*pb.OrderResponse, error) {
+	return s.transitionOrder(ctx, req, pb.OrderStatus_ORDER_STATUS_DELIVERED, "DeliverOrder")
+}
+
+// CancelOrder cancels an order that has not shipped, releasing its stock
+func (s *server) CancelOrder(ctx context.Context, req *pb.OrderTransitionRequest) (*pb.OrderResponse, error) {
+	return s.transitionOrder(ctx, req, pb.OrderStatus_ORDER_STATUS_CANCELLED, "CancelOrder")
+}
+
+// ReturnOrder records a shipped or delivered order coming back, restocking it
+func (s *server) ReturnOrder(ctx context.Context, req *pb.OrderTransitionRequest) (*pb.OrderResponse, error) {
+	return s.transitionOrder(ctx, req, pb.OrderStatus_ORDER_STATUS_RETURNED, "ReturnOrder")
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	pb "github.com/kingztech2019/proto_grpc/proto_inventory/grpc-inventory-proto"
+	"github.com/kingztech2019/proto_grpc/proto_inventory/store"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// orderCursor is the sort key stored in a ListOrders page token.
+type orderCursor struct {
+	OrderDate time.Time `json:"date"`
+	ID        string    `json:"id"`
+}
+
+// orderQuery turns the filters of a ListOrders request into a store query
+func orderQuery(req *pb.ListOrdersRequest) (store.OrderQuery, error) {
+	q := store.OrderQuery{ProductID: req.ProductId, Backordered: req.Backordered}
+	if req.OrderDateFrom != nil {
+		if err := req.OrderDateFrom.CheckValid(); err != nil {
+			return q, invalidArgument("order_date_from", err.Error())
+		}
+		q.From = req.OrderDateFrom.AsTime()
+	}
+	if req.OrderDateTo != nil {
+		if err := req.OrderDateTo.CheckValid(); err != nil {
+			return q, invalidArgument("order_date_to", err.Error())
+		}
+		q.To = req.OrderDateTo.AsTime()
+	}
+	if !q.From.IsZero() && !q.To.IsZero() && !q.From.Before(q.To) {
+		return q, invalidArgument("order_date_to", "must be after order_date_from")
+	}
+	if req.MinQuantity != nil && req.GetMinQuantity() < 1 {
+		return q, invalidArgument("min_quantity", "must be positive")
+	}
+	if req.MaxQuantity != nil && req.GetMaxQuantity() < 1 {
+		return q, invalidArgument("max_quantity", "must be positive")
+	}
+	if req.MinQuantity != nil && req.MaxQuantity != nil && req.GetMinQuantity() > req.GetMaxQuantity() {
+		return q, invalidArgument("max_quantity", "must not be less than min_quantity")
+	}
+	q.MinQuantity, q.MaxQuantity = req.GetMinQuantity(), req.GetMaxQuantity()
+
+	switch req.Status {
+	case pb.OrderStatus_ORDER_STATUS_UNSPECIFIED:
+	case pb.OrderStatus_ORDER_STATUS_PENDING:
+		// Orders stored before statuses existed count as pending.
+		q.Statuses = []pb.OrderStatus{pb.OrderStatus_ORDER_STATUS_UNSPECIFIED, pb.OrderStatus_ORDER_STATUS_PENDING}
+	default:
+		q.Statuses = []pb.OrderStatus{req.Status}
+	}
+	return q, nil
+}
+
+// ListOrders returns a page of orders matching the request filters, oldest
+// first
+func (s *server) ListOrders(ctx context.Context, req *pb.ListOrdersRequest) (*pb.ListOrdersResponse, error) {
+	size, err := pageSize(req.PageSize)
+	if err != nil {
+		return nil, invalidArgument("page_size", err.Error())
+	}
+	q, err := orderQuery(req)
+	if err != nil {
+		return nil, err
+	}
+	query := queryFingerprint(q.ProductID, q.From, q.To, q.MinQuantity, q.MaxQuantity, q.Statuses, q.Backordered)
+
+	if req.PageToken != "" {
+		var cursor orderCursor
+		if err := decodePageToken(req.PageToken, query, &cursor); err != nil {
+			return nil, invalidArgument("page_token", err.Error())
+		}
+		q.AfterDate, q.AfterID = cursor.OrderDate, cursor.ID
+	}
+	// One extra order tells whether another page follows.
+	q.Limit = size + 1
+
+	var orders []*pb.Order
+	err = s.store.View(ctx, func(tx store.Tx) error {
+		var err error
+		orders, err = tx.Orders().Query(q)
+		return err
+	})
+	if err != nil {
+		return nil, storeError(err)
+	}
+
+	res := &pb.ListOrdersResponse{}
+	if len(orders) > size {
+		orders = orders[:size]
+		last := orders[size-1]
+		res.NextPageToken, err = encodePageToken(query, orderCursor{OrderDate: last.OrderDate.AsTime(), ID: last.Id})
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+	}
+	for _, order := range orders {
+		s.normalizeOrder(order)
+	}
+	res.Orders = orders
+	return res, nil
+}
@@ -0,0 +1,359 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	pb "github.com/kingztech2019/proto_grpc/proto_inventory/grpc-inventory-proto"
+	"github.com/kingztech2019/proto_grpc/proto_inventory/store"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// compareLots orders lots for allocation: earliest expiry first and lots
+// that never expire last, then by when they were received
+func compareLots(a, b *pb.Lot) int {
+	switch {
+	case a.ExpireTime == nil && b.ExpireTime != nil:
+		return 1
+	case a.ExpireTime != nil && b.ExpireTime == nil:
+		return -1
+	case a.ExpireTime != nil:
+		if c := a.ExpireTime.AsTime().Compare(b.ExpireTime.AsTime()); c != 0 {
+			return c
+		}
+	}
+	if c := a.GetReceiveTime().AsTime().Compare(b.GetReceiveTime().AsTime()); c != 0 {
+		return c
+	}
+	return strings.Compare(a.Id, b.Id)
+}
+
+// isExpired reports whether the lot has expired by now
+func isExpired(lot *pb.Lot, now time.Time) bool {
+	return lot.ExpireTime != nil && !lot.ExpireTime.AsTime().After(now)
+}
+
+// lotsAt returns the product's lots at a warehouse that still hold stock,
+// in allocation order
+func lotsAt(tx store.Tx, productID, warehouseID string) ([]*pb.Lot, error) {
+	lots, err := tx.Lots().ListByProduct(productID)
+	if err != nil {
+		return nil, err
+	}
+	lots = slices.DeleteFunc(lots, func(l *pb.Lot) bool { return l.WarehouseId != warehouseID || l.Quantity == 0 })
+	slices.SortFunc(lots, compareLots)
+	return lots, nil
+}
+
+// allocatable returns how many units of product at a warehouse orders may
+// take: its stock less the units in expired lots
+func (s *server) allocatable(tx store.Tx, product *pb.Product, warehouseID string) (int32, error) {
+	available, err := s.stockAt(tx, product, warehouseID)
+	if err != nil {
+		return 0, err
+	}
+	lots, err := lotsAt(tx, product.Id, warehouseID)
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	for _, lot := range lots {
+		if isExpired(lot, now) {
+			available -= lot.Quantity
+		}
+	}
+	return available, nil
+}
+
+// allocateLots takes up to n units of a product from its unexpired lots at
+// a warehouse, first-expired-first-out, and records them in allocs. Any
+// units the lots cannot cover come from stock not tracked in a lot.
+func allocateLots(tx store.Tx, allocs *[]*pb.LotAllocation, productID, warehouseID string, n int32) error {
+	lots, err := lotsAt(tx, productID, warehouseID)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, lot := range lots {
+		if n == 0 {
+			break
+		}
+		if isExpired(lot, now) {
+			continue
+		}
+		take := min(lot.Quantity, n)
+		lot.Quantity -= take
+		n -= take
+		if err := tx.Lots().Update(lot); err != nil {
+			return err
+		}
+		*allocs = append(*allocs, &pb.LotAllocation{
+			ProductId:  productID,
+			LotId:      lot.Id,
+			LotNumber:  lot.LotNumber,
+			Quantity:   take,
+			ExpireTime: lot.ExpireTime,
+		})
+	}
+	return nil
+}
+
+// releaseLots gives up to n units of a product in allocs back to their
+// lots, most recently allocated first. Units from lots that are gone or at
+// another warehouse go back as untracked stock.
+func releaseLots(tx store.Tx, allocs *[]*pb.LotAllocation, productID, warehouseID string, n int32) error {
+	for i := len(*allocs) - 1; i >= 0 && n > 0; i-- {
+		alloc := (*allocs)[i]
+		if alloc.ProductId != productID {
+			continue
+		}
+		take := min(alloc.Quantity, n)
+		lot, err := tx.Lots().Get(alloc.LotId)
+		if err != nil && !errors.Is(err, store.ErrNotFound) {
+			return err
+		}
+		if err == nil && lot.WarehouseId == warehouseID {
+			lot.Quantity += take
+			if err := tx.Lots().Update(lot); err != nil {
+				return err
+			}
+		}
+		alloc.Quantity -= take
+		n -= take
+		if alloc.Quantity == 0 {
+			*allocs = slices.Delete(*allocs, i, i+1)
+		}
+	}
+	return nil
+}
+
+// depositLots puts up to n units of a product in allocs into the lots with
+// the same lot_number at a warehouse, earliest-expiring first, creating
+// the lots that do not exist there yet, and removes them from allocs. It is
+// the other half of allocateLots for units that move between warehouses.
+func depositLots(tx store.Tx, allocs *[]*pb.LotAllocation, productID, warehouseID string, n int32) error {
+	lots, err := tx.Lots().ListByProduct(productID)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < len(*allocs) && n > 0; {
+		alloc := (*allocs)[i]
+		if alloc.ProductId != productID {
+			i++
+			continue
+		}
+		take := min(alloc.Quantity, n)
+		j := slices.IndexFunc(lots, func(l *pb.Lot) bool {
+			return l.WarehouseId == warehouseID && l.LotNumber == alloc.LotNumber
+		})
+		if j >= 0 {
+			lots[j].Quantity += take
+			if err := tx.Lots().Update(lots[j]); err != nil {
+				return err
+			}
+		} else {
+			id, err := newID()
+			if err != nil {
+				return status.Errorf(codes.Internal, "failed to generate lot id: %v", err)
+			}
+			lot := &pb.Lot{
+				Id:          id,
+				ProductId:   productID,
+				WarehouseId: warehouseID,
+				LotNumber:   alloc.LotNumber,
+				Quantity:    take,
+				ExpireTime:  alloc.ExpireTime,
+				ReceiveTime: timestamppb.Now(),
+			}
+			if err := tx.Lots().Create(lot); err != nil {
+				return err
+			}
+			lots = append(lots, lot)
+		}
+		alloc.Quantity -= take
+		n -= take
+		if alloc.Quantity == 0 {
+			*allocs = slices.Delete(*allocs, i, i+1)
+		} else {
+			i++
+		}
+	}
+	return nil
+}
+
+// trimLots reduces the product's lots at a warehouse, earliest-expiring
+// first, until they hold no more than quantity units in total. It keeps the
+// lots within the warehouse's stock after a removal that named no lot.
+func trimLots(tx store.Tx, productID, warehouseID string, quantity int32) error {
+	lots, err := lotsAt(tx, productID, warehouseID)
+	if err != nil {
+		return err
+	}
+	excess := -quantity
+	for _, lot := range lots {
+		excess += lot.Quantity
+	}
+	for _, lot := range lots {
+		if excess <= 0 {
+			break
+		}
+		take := min(lot.Quantity, excess)
+		lot.Quantity -= take
+		excess -= take
+		if err := tx.Lots().Update(lot); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// productLots returns the product's lots that hold stock, in allocation
+// order
+func productLots(tx store.Tx, productID string) ([]*pb.Lot, error) {
+	lots, err := tx.Lots().ListByProduct(productID)
+	if err != nil {
+		return nil, err
+	}
+	lots = slices.DeleteFunc(lots, func(l *pb.Lot) bool { return l.Quantity == 0 })
+	slices.SortFunc(lots, compareLots)
+	return lots, nil
+}
+
+// ReceiveLot records a new lot and adds its units to the warehouse's stock
+func (s *server) ReceiveLot(ctx context.Context, req *pb.ReceiveLotRequest) (*pb.ReceiveLotResponse, error) {
+	l := req.Lot
+	switch {
+	case l == nil:
+		return nil, invalidArgument("lot", "is required")
+	case l.ProductId == "":
+		return nil, invalidArgument("lot.product_id", "is required")
+	case l.LotNumber == "":
+		return nil, invalidArgument("lot.lot_number", "is required")
+	case l.Quantity <= 0:
+		return nil, invalidArgument("lot.quantity", "must be positive")
+	}
+	if l.ExpireTime != nil {
+		if err := l.ExpireTime.CheckValid(); err != nil {
+			return nil, invalidArgument("lot.expire_time", err.Error())
+		}
+	}
+	key, err := idempotencyKey(ctx, req.IdempotencyKey)
+	if err != nil {
+		return nil, err
+	}
+	lot := proto.Clone(l).(*pb.Lot)
+	if lot.WarehouseId == "" {
+		lot.WarehouseId = s.defaultWarehouse
+	}
+	if lot.Id, err = newID(); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to generate lot id: %v", err)
+	}
+
+	res := &pb.ReceiveLotResponse{}
+	err = s.mutate(ctx, key, req, res, func(tx store.Tx) error {
+		product, err := s.getProduct(tx, lot.ProductId)
+		if err != nil {
+			return err
+		}
+		if product.Serialized {
+			return failedPrecondition("SERIALIZED", product.Id, fmt.Sprintf(
+				"stock of serialized product %q is added with ReceiveSerials", product.Id))
+		}
+		if err := checkUnitCost("unit_cost", req.UnitCost, product); err != nil {
+			return err
+		}
+		if _, err := getWarehouse(tx, lot.WarehouseId); err != nil {
+			return err
+		}
+		existing, err := tx.Lots().ListByProduct(lot.ProductId)
+		if err != nil {
+			return err
+		}
+		for _, other := range existing {
+			if other.WarehouseId == lot.WarehouseId && other.LotNumber == lot.LotNumber {
+				return alreadyExists(lotResource, lot.LotNumber)
+			}
+		}
+		lot.ReceiveTime = timestamppb.Now()
+		if err := tx.Lots().Create(lot); err != nil {
+			return err
+		}
+		movement := &pb.StockMovement{
+			WarehouseId: lot.WarehouseId,
+			Delta:       lot.Quantity,
+			Reason:      pb.StockMovementReason_RECEIVED,
+			Note:        req.Note,
+			LotId:       lot.Id,
+			UnitCost:    req.UnitCost,
+			Cause:       "ReceiveLot",
+		}
+		if _, err := s.moveStock(tx, product, movement); err != nil {
+			return err
+		}
+		res.Lot, res.Movement = lot, movement
+		return nil
+	})
+	if err != nil {
+		return nil, storeError(err)
+	}
+	return res, nil
+}
+
+// ListExpiringLots returns the lots holding stock that expire within the
+// requested horizon, including those already expired
+func (s *server) ListExpiringLots(ctx context.Context, req *pb.ListExpiringLotsRequest) (*pb.ListExpiringLotsResponse, error) {
+	var horizon time.Duration
+	if req.Horizon != nil {
+		if err := req.Horizon.CheckValid(); err != nil {
+			return nil, invalidArgument("horizon", err.Error())
+		}
+		if horizon = req.Horizon.AsDuration(); horizon < 0 {
+			return nil, invalidArgument("horizon", "must not be negative")
+		}
+	}
+
+	var lots []*pb.Lot
+	err := s.store.View(ctx, func(tx store.Tx) error {
+		var err error
+		lots, err = tx.Lots().ListExpiring(time.Now().Add(horizon))
+		return err
+	})
+	if err != nil {
+		return nil, storeError(err)
+	}
+	lots = slices.DeleteFunc(lots, func(l *pb.Lot) bool {
+		return req.ProductId != "" && l.ProductId != req.ProductId ||
+			req.WarehouseId != "" && l.WarehouseId != req.WarehouseId
+	})
+	return &pb.ListExpiringLotsResponse{Lots: lots}, nil
+}
+
+// adjustLot applies an AdjustStock delta to the lot it names and returns
+// the warehouse the adjustment applies to
+func adjustLot(tx store.Tx, req *pb.AdjustStockRequest) (string, error) {
+	lot, err := tx.Lots().Get(req.LotId)
+	if errors.Is(err, store.ErrNotFound) {
+		return "", notFound(lotResource, req.LotId)
+	}
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case lot.ProductId != req.ProductId:
+		return "", invalidArgument("lot_id", fmt.Sprintf("lot %q is of product %q", lot.Id, lot.ProductId))
+	case req.WarehouseId != "" && lot.WarehouseId != req.WarehouseId:
+		return "", invalidArgument("lot_id", fmt.Sprintf("lot %q is at warehouse %q", lot.Id, lot.WarehouseId))
+	case lot.Quantity+req.Delta < 0:
+		return "", failedPrecondition("STOCK", lot.Id, fmt.Sprintf(
+			"insufficient stock in lot %q: requested %d, available %d", lot.Id, -req.Delta, lot.Quantity))
+	}
+	lot.Quantity += req.Delta
+	return lot.WarehouseId, tx.Lots().Update(lot)
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	pb "github.com/kingztech2019/proto_grpc/proto_inventory/grpc-inventory-proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultCurrency = "USD"
+	nanosPerUnit    = 1_000_000_000
+)
+
+var currencyCode = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// validateMoney checks that m is a well-formed, non-negative amount
+func validateMoney(field string, m *pb.Money) error {
+	switch {
+	case !currencyCode.MatchString(m.CurrencyCode):
+		return invalidArgument(field+".currency_code", "must be a three-letter ISO 4217 code")
+	case m.Nanos <= -nanosPerUnit || m.Nanos >= nanosPerUnit:
+		return invalidArgument(field+".nanos", "must be between -999999999 and 999999999")
+	case m.Units > 0 && m.Nanos < 0 || m.Units < 0 && m.Nanos > 0:
+		return invalidArgument(field, "units and nanos must have the same sign")
+	case m.Units < 0 || m.Nanos < 0:
+		return invalidArgument(field, "must not be negative")
+	}
+	return nil
+}
+
+// moneyFromFloat converts a legacy float price. The float is read as the
+// shortest decimal that round-trips, so 12.99 becomes exactly 12.99 rather
+// than 12.9899997. NaN, infinities and amounts too large for units are
+// rejected.
+func moneyFromFloat(f float32, currency string) (*pb.Money, error) {
+	if math.IsNaN(float64(f)) || math.IsInf(float64(f), 0) {
+		return nil, fmt.Errorf("must be a finite number")
+	}
+	s := strconv.FormatFloat(float64(f), 'f', -1, 32)
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+	whole, frac, _ := strings.Cut(s, ".")
+	frac = (frac + "000000000")[:9]
+	units, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("is out of range")
+	}
+	nanos, err := strconv.ParseInt(frac, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("is out of range")
+	}
+	if negative {
+		units, nanos = -units, -nanos
+	}
+	return &pb.Money{CurrencyCode: currency, Units: units, Nanos: int32(nanos)}, nil
+}
+
+// storedMoney converts a float price read back from the store. Prices were
+// checked when they were written, so one that still cannot be converted
+// reads as zero rather than failing the read.
+func storedMoney(f float32, currency string) *pb.Money {
+	m, err := moneyFromFloat(f, currency)
+	if err != nil {
+		return &pb.Money{CurrencyCode: currency}
+	}
+	return m
+}
+
+// moneyFloat approximates m for the deprecated float fields
+func moneyFloat(m *pb.Money) float32 {
+	return float32(float64(m.GetUnits()) + float64(m.GetNanos())/nanosPerUnit)
+}
+
+// compareMoney orders amounts, ignoring their currency
+func compareMoney(a, b *pb.Money) int {
+	if c := cmp.Compare(a.GetUnits(), b.GetUnits()); c != 0 {
+		return c
+	}
+	return cmp.Compare(a.GetNanos(), b.GetNanos())
+}
+
+// addMoney returns a + b, which must be in the same currency
+func addMoney(a, b *pb.Money) (*pb.Money, error) {
+	if a.CurrencyCode != b.CurrencyCode {
+		return nil, fmt.Errorf("cannot add %s to %s", b.CurrencyCode, a.CurrencyCode)
+	}
+	if b.Units > 0 && a.Units > math.MaxInt64-b.Units || b.Units < 0 && a.Units < math.MinInt64-b.Units {
+		return nil, status.Error(codes.OutOfRange, "amount overflows")
+	}
+	return normalizeMoney(a.CurrencyCode, a.Units+b.Units, int64(a.Nanos)+int64(b.Nanos))
+}
+
+// mulMoney returns m * n for a non-negative n
+func mulMoney(m *pb.Money, n int32) (*pb.Money, error) {
+	if n == 0 {
+		return &pb.Money{CurrencyCode: m.CurrencyCode}, nil
+	}
+	if m.Units > math.MaxInt64/int64(n) || m.Units < math.MinInt64/int64(n) {
+		return nil, status.Error(codes.OutOfRange, "amount overflows")
+	}
+	return normalizeMoney(m.CurrencyCode, m.Units*int64(n), int64(m.Nanos)*int64(n))
+}
+
+// normalizeMoney carries whole units out of nanos and gives both parts the
+// same sign
+func normalizeMoney(currency string, units, nanos int64) (*pb.Money, error) {
+	carry := nanos / nanosPerUnit
+	if carry > 0 && units > math.MaxInt64-carry || carry < 0 && units < math.MinInt64-carry {
+		return nil, status.Error(codes.OutOfRange, "amount overflows")
+	}
+	units += carry
+	nanos %= nanosPerUnit
+	switch {
+	case units > 0 && nanos < 0:
+		units, nanos = units-1, nanos+nanosPerUnit
+	case units < 0 && nanos > 0:
+		units, nanos = units+1, nanos-nanosPerUnit
+	}
+	return &pb.Money{CurrencyCode: currency, Units: units, Nanos: int32(nanos)}, nil
+}
+
+// normalizeProductPrice makes price_money the authoritative price: a
+// product that only has the legacy float price gets it converted into
+// currency, and the float is rewritten from price_money for old clients.
+// It is applied both to products written by clients and to products read
+// from stores that predate price_money.
+func normalizeProductPrice(p *pb.Product, currency string) {
+	if p.PriceMoney == nil {
+		p.PriceMoney = storedMoney(p.Price, currency)
+	}
+	p.Price = moneyFloat(p.PriceMoney)
+}
+
+// normalizeOrderPrices converts the float prices of an order written
+// before money fields existed, and refreshes the float mirrors.
+func normalizeOrderPrices(o *pb.Order, currency string) {
+	for _, line := range o.Lines {
+		if line.UnitPriceMoney == nil {
+			line.UnitPriceMoney = storedMoney(line.UnitPrice, currency)
+		}
+		if line.LineTotalMoney == nil {
+			line.LineTotalMoney = storedMoney(line.LineTotal, currency)
+		}
+		line.UnitPrice = moneyFloat(line.UnitPriceMoney)
+		line.LineTotal = moneyFloat(line.LineTotalMoney)
+	}
+	if o.SubtotalMoney == nil {
+		o.SubtotalMoney = storedMoney(o.Subtotal, currency)
+	}
+	if o.TotalMoney == nil {
+		o.TotalMoney = storedMoney(o.Total, currency)
+	}
+	o.Subtotal = moneyFloat(o.SubtotalMoney)
+	o.Total = moneyFloat(o.TotalMoney)
+}
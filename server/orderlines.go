@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+
+	pb "github.com/kingztech2019/proto_grpc/proto_inventory/grpc-inventory-proto"
+	"github.com/kingztech2019/proto_grpc/proto_inventory/store"
+	"google.golang.org/protobuf/proto"
+)
+
+// validateOrder checks the fields every stored order must carry. An order
+// either lists its lines or, as before lines existed, names a single
+// product_id and quantity.
+func validateOrder(order *pb.Order) error {
+	if order == nil {
+		return invalidArgument("order", "is required")
+	}
+	if len(order.Lines) == 0 {
+		switch {
+		case order.ProductId == "":
+			return invalidArgument("order.lines", "at least one line is required")
+		case order.Quantity <= 0:
+			return invalidArgument("order.quantity", "must be positive")
+		}
+		return nil
+	}
+	serials := make(map[serialRef]bool)
+	for i, line := range order.Lines {
+		switch {
+		case line == nil:
+			return invalidArgument(fmt.Sprintf("order.lines[%d]", i), "is required")
+		case line.ProductId == "":
+			return invalidArgument(fmt.Sprintf("order.lines[%d].product_id", i), "is required")
+		case line.Quantity <= 0:
+			return invalidArgument(fmt.Sprintf("order.lines[%d].quantity", i), "must be positive")
+		case len(line.SerialNumbers) > int(line.Quantity):
+			return invalidArgument(fmt.Sprintf("order.lines[%d].serial_numbers", i), "must not outnumber quantity")
+		}
+		for j, serial := range line.SerialNumbers {
+			ref := serialRef{line.ProductId, serial}
+			switch {
+			case serial == "":
+				return invalidArgument(fmt.Sprintf("order.lines[%d].serial_numbers[%d]", i, j), "must not be empty")
+			case serials[ref]:
+				return invalidArgument(fmt.Sprintf("order.lines[%d].serial_numbers[%d]", i, j),
+					fmt.Sprintf("serial number %q is listed twice", serial))
+			}
+			serials[ref] = true
+		}
+	}
+	return nil
+}
+
+// normalizeLines turns a single-product order into a one-line order and
+// mirrors a single line back into product_id and quantity for clients that
+// predate lines. Orders with several lines leave those fields empty.
+func normalizeLines(order *pb.Order) {
+	if len(order.Lines) == 0 && order.ProductId != "" {
+		order.Lines = []*pb.OrderLine{{ProductId: order.ProductId, Quantity: order.Quantity}}
+	}
+	order.ProductId, order.Quantity = "", 0
+	if len(order.Lines) == 1 {
+		order.ProductId, order.Quantity = order.Lines[0].ProductId, order.Lines[0].Quantity
+	}
+}
+
+// lineDeltas sums the order's reserved quantities per product, leaving out
+// backordered units, negated if release is set, in the form reserveStock
+// takes
+func lineDeltas(order *pb.Order, release bool) map[string]int32 {
+	deltas := make(map[string]int32, len(order.Lines))
+	for _, line := range order.Lines {
+		reserved := line.Quantity - line.BackorderedQuantity
+		if release {
+			deltas[line.ProductId] -= reserved
+		} else {
+			deltas[line.ProductId] += reserved
+		}
+	}
+	return deltas
+}
+
+// priceOrder snapshots each line's unit price and computes the order
+// totals. Lines for a product already on previous keep the price they were
+// ordered at; new lines take the product's current price. previous is nil
+// for a new order. Every line must be priced in the same currency.
+func (s *server) priceOrder(tx store.Tx, order, previous *pb.Order) error {
+	snapshot := make(map[string]*pb.Money)
+	if previous != nil {
+		for _, line := range previous.Lines {
+			snapshot[line.ProductId] = line.UnitPriceMoney
+		}
+	}
+
+	var subtotal *pb.Money
+	for _, line := range order.Lines {
+		price, ok := snapshot[line.ProductId]
+		if !ok {
+			product, err := s.getProduct(tx, line.ProductId)
+			if err != nil {
+				return err
+			}
+			price = product.PriceMoney
+			snapshot[line.ProductId] = price
+		}
+		if subtotal == nil {
+			subtotal = &pb.Money{CurrencyCode: price.CurrencyCode}
+		}
+		if price.CurrencyCode != subtotal.CurrencyCode {
+			return failedPrecondition("CURRENCY", line.ProductId, fmt.Sprintf(
+				"product %q is priced in %s but the order is in %s",
+				line.ProductId, price.CurrencyCode, subtotal.CurrencyCode))
+		}
+		total, err := mulMoney(price, line.Quantity)
+		if err != nil {
+			return err
+		}
+		if subtotal, err = addMoney(subtotal, total); err != nil {
+			return err
+		}
+		line.UnitPriceMoney, line.LineTotalMoney = proto.Clone(price).(*pb.Money), total
+	}
+	order.SubtotalMoney = subtotal
+	order.TotalMoney = proto.Clone(subtotal).(*pb.Money)
+	normalizeOrderPrices(order, s.currency)
+	return nil
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	pb "github.com/kingztech2019/proto_grpc/proto_inventory/grpc-inventory-proto"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 1000
+)
+
+// pageToken is the opaque cursor handed to clients as next_page_token.
+// It records the sort key of the last item returned rather than an offset,
+// so pages stay stable while items are inserted, updated or deleted.
+type pageToken struct {
+	Query string          `json:"q"` // fingerprint of the filters and ordering
+	Last  json.RawMessage `json:"l"` // sort key of the last item on the page
+}
+
+// pageSize clamps a requested page size to the server limits.
+func pageSize(requested int32) (int, error) {
+	switch {
+	case requested < 0:
+		return 0, fmt.Errorf("page_size must not be negative")
+	case requested == 0:
+		return defaultPageSize, nil
+	case requested > maxPageSize:
+		return maxPageSize, nil
+	}
+	return int(requested), nil
+}
+
+// queryFingerprint hashes the parts of a list request that must not change
+// between pages.
+func queryFingerprint(parts ...any) string {
+	h := sha256.New()
+	for _, p := range parts {
+		fmt.Fprintf(h, "%v\x00", p)
+	}
+	return hex.EncodeToString(h.Sum(nil)[:8])
+}
+
+func encodePageToken(query string, last any) (string, error) {
+	raw, err := json.Marshal(last)
+	if err != nil {
+		return "", err
+	}
+	b, err := json.Marshal(pageToken{Query: query, Last: raw})
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// decodePageToken unpacks a token into last. It fails if the token is
+// malformed or was issued for a different query.
+func decodePageToken(token, query string, last any) error {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return fmt.Errorf("malformed page_token")
+	}
+	var t pageToken
+	if err := json.Unmarshal(b, &t); err != nil {
+		return fmt.Errorf("malformed page_token")
+	}
+	if t.Query != query {
+		return fmt.Errorf("page_token does not match the request filters")
+	}
+	if err := json.Unmarshal(t.Last, last); err != nil {
+		return fmt.Errorf("malformed page_token")
+	}
+	return nil
+}
+
+// productSortKey is one clause of a ListProducts order_by.
+type productSortKey struct {
+	field string
+	desc  bool
+}
+
+// productOrder is a parsed order_by clause. Products are always tie-broken
+// by ascending id so the ordering is total.
+type productOrder []productSortKey
+
+var productSortFields = map[string]bool{
+	"id":              true,
+	"name":            true,
+	"price":           true,
+	"inventory_level": true,
+}
+
+// parseProductOrderBy parses a comma-separated list of fields, each
+// optionally followed by "asc" or "desc".
+func parseProductOrderBy(orderBy string) (productOrder, error) {
+	var order productOrder
+	if strings.TrimSpace(orderBy) == "" {
+		return order, nil
+	}
+	for _, clause := range strings.Split(orderBy, ",") {
+		parts := strings.Fields(clause)
+		if len(parts) == 0 || len(parts) > 2 {
+			return nil, fmt.Errorf("invalid order_by clause %q", strings.TrimSpace(clause))
+		}
+		key := productSortKey{field: parts[0]}
+		if !productSortFields[key.field] {
+			return nil, fmt.Errorf("cannot order by unknown field %q", key.field)
+		}
+		if len(parts) == 2 {
+			switch strings.ToLower(parts[1]) {
+			case "asc":
+			case "desc":
+				key.desc = true
+			default:
+				return nil, fmt.Errorf("invalid sort direction %q", parts[1])
+			}
+		}
+		order = append(order, key)
+	}
+	return order, nil
+}
+
+func (o productOrder) String() string {
+	var b strings.Builder
+	for i, k := range o {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		b.WriteString(k.field)
+		if k.desc {
+			b.WriteString(" desc")
+		}
+	}
+	return b.String()
+}
+
+// compare orders a before b according to o.
+func (o productOrder) compare(a, b *pb.Product) int {
+	for _, k := range o {
+		var c int
+		switch k.field {
+		case "id":
+			c = cmp.Compare(a.Id, b.Id)
+		case "name":
+			c = cmp.Compare(a.Name, b.Name)
+		case "price":
+			c = compareMoney(a.PriceMoney, b.PriceMoney)
+		case "inventory_level":
+			c = cmp.Compare(a.InventoryLevel, b.InventoryLevel)
+		}
+		if k.desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return cmp.Compare(a.Id, b.Id)
+}
+
+// productCursor is the sort key stored in a ListProducts page token.
+type productCursor struct {
+	ID             string `json:"id"`
+	Name           string `json:"name,omitempty"`
+	PriceUnits     int64  `json:"price_units,omitempty"`
+	PriceNanos     int32  `json:"price_nanos,omitempty"`
+	InventoryLevel int32  `json:"inventory_level,omitempty"`
+}
+
+func newProductCursor(p *pb.Product) productCursor {
+	return productCursor{
+		ID:             p.Id,
+		Name:           p.Name,
+		PriceUnits:     p.PriceMoney.GetUnits(),
+		PriceNanos:     p.PriceMoney.GetNanos(),
+		InventoryLevel: p.InventoryLevel,
+	}
+}
+
+func (c productCursor) product() *pb.Product {
+	return &pb.Product{
+		Id:             c.ID,
+		Name:           c.Name,
+		PriceMoney:     &pb.Money{Units: c.PriceUnits, Nanos: c.PriceNanos},
+		InventoryLevel: c.InventoryLevel,
+	}
+}
+
+// matchesProductFilter reports whether p satisfies the filters in req.
+// minPrice and maxPrice are req's price bounds already converted to money.
+func matchesProductFilter(req *pb.ListProductsRequest, minPrice, maxPrice *pb.Money, p *pb.Product) bool {
+	if req.NameContains != "" && !strings.Contains(strings.ToLower(p.Name), strings.ToLower(req.NameContains)) {
+		return false
+	}
+	if minPrice != nil && compareMoney(p.PriceMoney, minPrice) < 0 {
+		return false
+	}
+	if maxPrice != nil && compareMoney(p.PriceMoney, maxPrice) > 0 {
+		return false
+	}
+	if req.MinInventoryLevel != nil && p.InventoryLevel < req.GetMinInventoryLevel() {
+		return false
+	}
+	if req.MaxInventoryLevel != nil && p.InventoryLevel > req.GetMaxInventoryLevel() {
+		return false
+	}
+	return true
+}
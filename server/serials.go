@@ -0,0 +1,542 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	pb "github.com/kingztech2019/proto_grpc/proto_inventory/grpc-inventory-proto"
+	"github.com/kingztech2019/proto_grpc/proto_inventory/store"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// serialRef names one unit of a serialized product
+type serialRef struct {
+	productID, serial string
+}
+
+// orderSerials returns the serial numbers assigned to the order's lines, in
+// line order
+func orderSerials(order *pb.Order) []serialRef {
+	var refs []serialRef
+	for _, line := range order.GetLines() {
+		for _, serial := range line.SerialNumbers {
+			refs = append(refs, serialRef{line.ProductId, serial})
+		}
+	}
+	return refs
+}
+
+// getSerial loads a serial number, reporting a missing one as NotFound
+func getSerial(tx store.Tx, ref serialRef) (*pb.SerialNumber, error) {
+	sn, err := tx.Serials().Get(ref.productID, ref.serial)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, notFound(serialResource, ref.serial)
+	}
+	return sn, err
+}
+
+// recordSerial appends an event involving orderID, if any, to the serial
+// number's history and stores it
+func recordSerial(tx store.Tx, sn *pb.SerialNumber, event pb.SerialEventType, orderID, cause string) error {
+	sn.History = append(sn.History, &pb.SerialEvent{
+		Type:        event,
+		WarehouseId: sn.WarehouseId,
+		OrderId:     orderID,
+		Cause:       cause,
+		Time:        timestamppb.Now(),
+	})
+	return tx.Serials().Update(sn)
+}
+
+// validateSerialNumbers checks that serials lists each serial number once
+// and none is empty
+func validateSerialNumbers(field string, serials []string) error {
+	seen := make(map[string]bool, len(serials))
+	for i, serial := range serials {
+		switch {
+		case serial == "":
+			return invalidArgument(fmt.Sprintf("%s[%d]", field, i), "must not be empty")
+		case seen[serial]:
+			return invalidArgument(fmt.Sprintf("%s[%d]", field, i),
+				fmt.Sprintf("serial number %q is listed twice", serial))
+		}
+		seen[serial] = true
+	}
+	return nil
+}
+
+// checkSerialCount fails unless serials names one serial number for each
+// of quantity units of a serialized product, or none for any other product
+func checkSerialCount(product *pb.Product, serials []string, quantity int32) error {
+	switch {
+	case !product.Serialized && len(serials) > 0:
+		return failedPrecondition("SERIALIZED", product.Id, fmt.Sprintf("product %q is not serialized", product.Id))
+	case product.Serialized && int32(len(serials)) != quantity:
+		return failedPrecondition("SERIAL_NUMBERS", product.Id, fmt.Sprintf(
+			"serialized product %q needs one serial number per unit: %d units, %d serial numbers",
+			product.Id, quantity, len(serials)))
+	}
+	return nil
+}
+
+// checkSerializedChange refuses to turn serial tracking on or off while the
+// product has stock, which would leave units on hand without serial numbers
+// or serial numbers without units behind them
+func checkSerializedChange(tx store.Tx, existing, product *pb.Product) error {
+	if existing.Serialized == product.Serialized {
+		return nil
+	}
+	refuse := func(why string) error {
+		return failedPrecondition("SERIALIZED", existing.Id, fmt.Sprintf(
+			"serialized cannot change while product %q %s", existing.Id, why))
+	}
+	if existing.InventoryLevel != 0 {
+		return refuse("has stock")
+	}
+	orders, err := tx.Orders().ListByProduct(existing.Id)
+	if err != nil {
+		return err
+	}
+	for _, order := range orders {
+		if holdsStock(order) {
+			return refuse(fmt.Sprintf("has stock reserved by order %q", order.Id))
+		}
+	}
+	hold, err := activeHoldOn(tx, existing.Id, "")
+	if err != nil {
+		return err
+	}
+	if hold != nil {
+		return refuse(fmt.Sprintf("is held by hold %q", hold.Id))
+	}
+	transfer, err := transferThrough(tx, existing.Id, "")
+	if err != nil {
+		return err
+	}
+	if transfer != nil {
+		return refuse(fmt.Sprintf("is in transit on transfer %q", transfer.Id))
+	}
+	return nil
+}
+
+// inStockAt fails unless the serial number is IN_STOCK at warehouseID
+func inStockAt(sn *pb.SerialNumber, warehouseID string) error {
+	switch {
+	case sn.Status == pb.SerialStatus_SERIAL_STATUS_ASSIGNED:
+		return failedPrecondition("SERIAL_STATUS", sn.SerialNumber, fmt.Sprintf(
+			"serial number %q of product %q is already assigned to order %q", sn.SerialNumber, sn.ProductId, sn.OrderId))
+	case sn.Status != pb.SerialStatus_SERIAL_STATUS_IN_STOCK:
+		return failedPrecondition("SERIAL_STATUS", sn.SerialNumber, fmt.Sprintf(
+			"serial number %q of product %q is %s", sn.SerialNumber, sn.ProductId, sn.Status))
+	case sn.WarehouseId != warehouseID:
+		return failedPrecondition("SERIAL_WAREHOUSE", sn.SerialNumber, fmt.Sprintf(
+			"serial number %q of product %q is at warehouse %q, not %q",
+			sn.SerialNumber, sn.ProductId, sn.WarehouseId, warehouseID))
+	}
+	return nil
+}
+
+// assignSerials brings the serial numbers held for an order in line with
+// its lines: serials previous had that order no longer lists are put back
+// in stock, and new ones must be IN_STOCK at the order's warehouse and are
+// assigned to it. previous is nil for a new order.
+func (s *server) assignSerials(tx store.Tx, order, previous *pb.Order, cause string) error {
+	kept := make(map[serialRef]bool)
+	if previous != nil && previous.WarehouseId == order.WarehouseId {
+		for _, ref := range orderSerials(order) {
+			kept[ref] = true
+		}
+	}
+	held := make(map[serialRef]bool)
+	if previous != nil {
+		for _, ref := range orderSerials(previous) {
+			if kept[ref] {
+				held[ref] = true
+			} else if err := unassignSerial(tx, ref, previous.Id, cause); err != nil {
+				return err
+			}
+		}
+	}
+
+	serialized := make(map[string]bool)
+	for _, ref := range orderSerials(order) {
+		if held[ref] {
+			continue
+		}
+		if _, ok := serialized[ref.productID]; !ok {
+			product, err := s.getProduct(tx, ref.productID)
+			if err != nil {
+				return err
+			}
+			serialized[ref.productID] = product.Serialized
+		}
+		if !serialized[ref.productID] {
+			return failedPrecondition("SERIALIZED", ref.productID,
+				fmt.Sprintf("product %q is not serialized", ref.productID))
+		}
+		sn, err := getSerial(tx, ref)
+		if err != nil {
+			return err
+		}
+		if err := inStockAt(sn, order.WarehouseId); err != nil {
+			return err
+		}
+		sn.Status, sn.OrderId = pb.SerialStatus_SERIAL_STATUS_ASSIGNED, order.Id
+		if err := recordSerial(tx, sn, pb.SerialEventType_SERIAL_EVENT_TYPE_ASSIGNED, order.Id, cause); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unassignSerial puts a serial number assigned to an order back in stock.
+// Serials that are gone or no longer assigned to the order are left alone.
+func unassignSerial(tx store.Tx, ref serialRef, orderID, cause string) error {
+	sn, err := tx.Serials().Get(ref.productID, ref.serial)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if sn.Status != pb.SerialStatus_SERIAL_STATUS_ASSIGNED || sn.OrderId != orderID {
+		return nil
+	}
+	sn.Status, sn.OrderId = pb.SerialStatus_SERIAL_STATUS_IN_STOCK, ""
+	return recordSerial(tx, sn, pb.SerialEventType_SERIAL_EVENT_TYPE_UNASSIGNED, orderID, cause)
+}
+
+// releaseSerials puts every serial number assigned to the order back in
+// stock
+func releaseSerials(tx store.Tx, order *pb.Order, cause string) error {
+	for _, ref := range orderSerials(order) {
+		if err := unassignSerial(tx, ref, order.Id, cause); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sellSerials marks the order's serial numbers SOLD. Every unit of a
+// serialized product on the order must have a serial number by then.
+func (s *server) sellSerials(tx store.Tx, order *pb.Order, cause string) error {
+	for _, line := range order.Lines {
+		if int32(len(line.SerialNumbers)) == line.Quantity {
+			continue
+		}
+		product, err := tx.Products().Get(line.ProductId)
+		if errors.Is(err, store.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if product.Serialized {
+			return failedPrecondition("SERIAL_NUMBERS", order.Id, fmt.Sprintf(
+				"order %q has %d of %d serial numbers assigned for product %q",
+				order.Id, len(line.SerialNumbers), line.Quantity, line.ProductId))
+		}
+	}
+	for _, ref := range orderSerials(order) {
+		sn, err := getSerial(tx, ref)
+		if err != nil {
+			return err
+		}
+		sn.Status = pb.SerialStatus_SERIAL_STATUS_SOLD
+		if err := recordSerial(tx, sn, pb.SerialEventType_SERIAL_EVENT_TYPE_SOLD, order.Id, cause); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// returnSerials puts the serial numbers sold on the order back in stock at
+// the order's warehouse, or the default one if it has since been deleted
+func (s *server) returnSerials(tx store.Tx, order *pb.Order, cause string) error {
+	warehouseID := order.WarehouseId
+	_, err := tx.Warehouses().Get(warehouseID)
+	if errors.Is(err, store.ErrNotFound) {
+		warehouseID = s.defaultWarehouse
+	} else if err != nil {
+		return err
+	}
+	for _, ref := range orderSerials(order) {
+		sn, err := tx.Serials().Get(ref.productID, ref.serial)
+		if errors.Is(err, store.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if sn.Status != pb.SerialStatus_SERIAL_STATUS_SOLD || sn.OrderId != order.Id {
+			continue
+		}
+		sn.Status, sn.OrderId, sn.WarehouseId = pb.SerialStatus_SERIAL_STATUS_IN_STOCK, "", warehouseID
+		if err := recordSerial(tx, sn, pb.SerialEventType_SERIAL_EVENT_TYPE_RETURNED, order.Id, cause); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeOffSerials marks serial numbers removed from stock at a warehouse
+// as damaged or lost WRITTEN_OFF. Each must be IN_STOCK there.
+func writeOffSerials(tx store.Tx, productID string, serials []string, warehouseID, cause string) error {
+	for _, serial := range serials {
+		sn, err := getSerial(tx, serialRef{productID, serial})
+		if err != nil {
+			return err
+		}
+		if err := inStockAt(sn, warehouseID); err != nil {
+			return err
+		}
+		sn.Status = pb.SerialStatus_SERIAL_STATUS_WRITTEN_OFF
+		if err := recordSerial(tx, sn, pb.SerialEventType_SERIAL_EVENT_TYPE_WRITTEN_OFF, "", cause); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordTransferSerial appends a transfer step to the serial number's
+// history and stores it
+func recordTransferSerial(tx store.Tx, sn *pb.SerialNumber, event pb.SerialEventType, transferID, cause string) error {
+	sn.History = append(sn.History, &pb.SerialEvent{
+		Type:        event,
+		WarehouseId: sn.WarehouseId,
+		TransferId:  transferID,
+		Cause:       cause,
+		Time:        timestamppb.Now(),
+	})
+	return tx.Serials().Update(sn)
+}
+
+// shipSerials puts serial numbers leaving the transfer's source IN_TRANSIT
+// on it. Each must be IN_STOCK at the source.
+func shipSerials(tx store.Tx, transfer *pb.Transfer, productID string, serials []string, cause string) error {
+	for _, serial := range serials {
+		sn, err := getSerial(tx, serialRef{productID, serial})
+		if err != nil {
+			return err
+		}
+		if err := inStockAt(sn, transfer.SourceWarehouseId); err != nil {
+			return err
+		}
+		sn.Status, sn.TransferId = pb.SerialStatus_SERIAL_STATUS_IN_TRANSIT, transfer.Id
+		if err := recordTransferSerial(tx, sn, pb.SerialEventType_SERIAL_EVENT_TYPE_TRANSFERRED_OUT, transfer.Id, cause); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// inTransitSerials returns the serial numbers of a transfer line that are
+// still in transit on it, in the order the line lists them
+func inTransitSerials(tx store.Tx, transfer *pb.Transfer, line *pb.TransferLine) ([]string, error) {
+	var serials []string
+	for _, serial := range line.SerialNumbers {
+		sn, err := tx.Serials().Get(line.ProductId, serial)
+		if errors.Is(err, store.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if sn.Status == pb.SerialStatus_SERIAL_STATUS_IN_TRANSIT && sn.TransferId == transfer.Id {
+			serials = append(serials, serial)
+		}
+	}
+	return serials, nil
+}
+
+// landSerials puts serial numbers in transit on a transfer IN_STOCK at a
+// warehouse: the destination when they arrive, or the source when the
+// transfer is cancelled
+func landSerials(tx store.Tx, transfer *pb.Transfer, productID string, serials []string, warehouseID, cause string) error {
+	for _, serial := range serials {
+		sn, err := getSerial(tx, serialRef{productID, serial})
+		if err != nil {
+			return err
+		}
+		if sn.Status != pb.SerialStatus_SERIAL_STATUS_IN_TRANSIT || sn.TransferId != transfer.Id {
+			return failedPrecondition("SERIAL_STATUS", serial, fmt.Sprintf(
+				"serial number %q of product %q is not in transit on transfer %q", serial, productID, transfer.Id))
+		}
+		sn.Status, sn.TransferId, sn.WarehouseId = pb.SerialStatus_SERIAL_STATUS_IN_STOCK, "", warehouseID
+		if err := recordTransferSerial(tx, sn, pb.SerialEventType_SERIAL_EVENT_TYPE_TRANSFERRED_IN, transfer.Id, cause); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// transitionSerials applies an order lifecycle step to the order's serial
+// numbers: shipping sells them, cancelling frees them and returning puts
+// them back in stock
+func (s *server) transitionSerials(tx store.Tx, order *pb.Order, to pb.OrderStatus, cause string) error {
+	switch to {
+	case pb.OrderStatus_ORDER_STATUS_SHIPPED:
+		return s.sellSerials(tx, order, cause)
+	case pb.OrderStatus_ORDER_STATUS_CANCELLED:
+		return releaseSerials(tx, order, cause)
+	case pb.OrderStatus_ORDER_STATUS_RETURNED:
+		return s.returnSerials(tx, order, cause)
+	}
+	return nil
+}
+
+// ReceiveSerials registers units of a serialized product at a warehouse
+// and adds them to its stock
+func (s *server) ReceiveSerials(ctx context.Context, req *pb.ReceiveSerialsRequest) (*pb.ReceiveSerialsResponse, error) {
+	switch {
+	case req.ProductId == "":
+		return nil, invalidArgument("product_id", "is required")
+	case len(req.SerialNumbers) == 0:
+		return nil, invalidArgument("serial_numbers", "at least one serial number is required")
+	}
+	if err := validateSerialNumbers("serial_numbers", req.SerialNumbers); err != nil {
+		return nil, err
+	}
+	key, err := idempotencyKey(ctx, req.IdempotencyKey)
+	if err != nil {
+		return nil, err
+	}
+	warehouseID := req.WarehouseId
+	if warehouseID == "" {
+		warehouseID = s.defaultWarehouse
+	}
+
+	res := &pb.ReceiveSerialsResponse{}
+	err = s.mutate(ctx, key, req, res, func(tx store.Tx) error {
+		product, err := s.getProduct(tx, req.ProductId)
+		if err != nil {
+			return err
+		}
+		if !product.Serialized {
+			return failedPrecondition("SERIALIZED", product.Id, fmt.Sprintf("product %q is not serialized", product.Id))
+		}
+		if err := checkUnitCost("unit_cost", req.UnitCost, product); err != nil {
+			return err
+		}
+		if _, err := getWarehouse(tx, warehouseID); err != nil {
+			return err
+		}
+		res.SerialNumbers = nil
+		for _, serial := range req.SerialNumbers {
+			sn, err := tx.Serials().Get(product.Id, serial)
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				sn = &pb.SerialNumber{ProductId: product.Id, SerialNumber: serial}
+				if err := tx.Serials().Create(sn); err != nil {
+					return err
+				}
+			case err != nil:
+				return err
+			case sn.Status != pb.SerialStatus_SERIAL_STATUS_SOLD && sn.Status != pb.SerialStatus_SERIAL_STATUS_WRITTEN_OFF:
+				return alreadyExists(serialResource, serial)
+			}
+			sn.Status, sn.OrderId, sn.WarehouseId = pb.SerialStatus_SERIAL_STATUS_IN_STOCK, "", warehouseID
+			if err := recordSerial(tx, sn, pb.SerialEventType_SERIAL_EVENT_TYPE_RECEIVED, "", "ReceiveSerials"); err != nil {
+				return err
+			}
+			res.SerialNumbers = append(res.SerialNumbers, sn)
+		}
+		movement := &pb.StockMovement{
+			WarehouseId: warehouseID,
+			Delta:       int32(len(req.SerialNumbers)),
+			Reason:      pb.StockMovementReason_RECEIVED,
+			Note:        req.Note,
+			UnitCost:    req.UnitCost,
+			Cause:       "ReceiveSerials",
+		}
+		if _, err := s.moveStock(tx, product, movement); err != nil {
+			return err
+		}
+		res.Movement = movement
+		return nil
+	})
+	if err != nil {
+		return nil, storeError(err)
+	}
+	return res, nil
+}
+
+// AssignSerials adds serial numbers to the lines of an order for one
+// product, filling each line up to its quantity in turn
+func (s *server) AssignSerials(ctx context.Context, req *pb.AssignSerialsRequest) (*pb.OrderResponse, error) {
+	switch {
+	case req.OrderId == "":
+		return s.orderError(invalidArgument("order_id", "is required"))
+	case req.ProductId == "":
+		return s.orderError(invalidArgument("product_id", "is required"))
+	case len(req.SerialNumbers) == 0:
+		return s.orderError(invalidArgument("serial_numbers", "at least one serial number is required"))
+	}
+	key, err := idempotencyKey(ctx, req.IdempotencyKey)
+	if err != nil {
+		return s.orderError(err)
+	}
+
+	res := &pb.OrderResponse{}
+	err = s.mutate(ctx, key, req, res, func(tx store.Tx) error {
+		order, err := s.getOrder(tx, req.OrderId)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(orderResource, order.Id, req.ExpectedVersion, order.Version); err != nil {
+			return err
+		}
+		if !holdsStock(order) {
+			return failedPrecondition("ORDER_STATUS", order.Id,
+				fmt.Sprintf("order %q is %s and its serial numbers can no longer be changed", order.Id, orderStatus(order)))
+		}
+		previous := proto.Clone(order).(*pb.Order)
+		pending, found := req.SerialNumbers, false
+		for _, line := range order.Lines {
+			if line.ProductId != req.ProductId {
+				continue
+			}
+			found = true
+			n := min(len(pending), int(line.Quantity)-len(line.SerialNumbers))
+			line.SerialNumbers = append(line.SerialNumbers, pending[:n]...)
+			pending = pending[n:]
+		}
+		switch {
+		case !found:
+			return invalidArgument("product_id", fmt.Sprintf("product %q is not on order %q", req.ProductId, order.Id))
+		case len(pending) > 0:
+			return failedPrecondition("SERIAL_NUMBERS", order.Id, fmt.Sprintf(
+				"order %q has room for %d more serial numbers of product %q, not %d",
+				order.Id, len(req.SerialNumbers)-len(pending), req.ProductId, len(req.SerialNumbers)))
+		}
+		if err := validateOrder(order); err != nil {
+			return err
+		}
+		if err := s.assignSerials(tx, order, previous, "AssignSerials"); err != nil {
+			return err
+		}
+		order.Version++
+		res.Order, res.Status, res.Message = order, "success", "Serial numbers assigned"
+		return tx.Orders().Update(order)
+	})
+	if err != nil {
+		return s.orderError(storeError(err))
+	}
+	return res, nil
+}
+
+// GetSerialNumber fetches a serial number with its full history
+func (s *server) GetSerialNumber(ctx context.Context, req *pb.GetSerialNumberRequest) (*pb.SerialNumber, error) {
+	var sn *pb.SerialNumber
+	err := s.store.View(ctx, func(tx store.Tx) error {
+		var err error
+		sn, err = getSerial(tx, serialRef{req.ProductId, req.SerialNumber})
+		return err
+	})
+	if err != nil {
+		return nil, storeError(err)
+	}
+	return sn, nil
+}
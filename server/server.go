@@ -2,112 +2,872 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"net"
-	"sync"
+	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+	"time"
 
+	"github.com/google/uuid"
 	pb "github.com/kingztech2019/proto_grpc/proto_inventory/grpc-inventory-proto" // Update this import path
+	"github.com/kingztech2019/proto_grpc/proto_inventory/store"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
-// In-memory storage for products and orders
-var (
-	productStore = make(map[string]*pb.Product)
-	orderStore   = make(map[string]*pb.Order)
-	mu           sync.Mutex
-)
+// config holds the server settings taken from command-line flags
+type config struct {
+	// legacyStatus reports failures through the response status/message
+	// fields with a nil error, for callers written before the service
+	// returned gRPC status codes.
+	legacyStatus bool
+	// eventRetention is the number of inventory events kept for
+	// WatchInventory clients to resume from.
+	eventRetention int
+	// idempotencyWindow is how long responses to requests carrying an
+	// idempotency key are kept for retries.
+	idempotencyWindow time.Duration
+	// currency is the ISO 4217 code given to legacy float prices.
+	currency string
+	// defaultWarehouse holds stock not assigned to any other warehouse.
+	defaultWarehouse string
+	// alertWebhook, when set, receives every alert as a JSON POST instead
+	// of the alert being logged.
+	alertWebhook string
+	// holdTTL is how long a cart hold lasts when the request gives no ttl.
+	holdTTL time.Duration
+}
 
 // Server struct implementing pb.InventoryServiceServer
 type server struct {
 	pb.UnimplementedInventoryServiceServer
+	config
+
+	store    store.Store
+	events   *eventHub
+	notifier notifier
 }
 
-// GetProduct fetches a product by its ID
-func (s *server) GetProduct(ctx context.Context, req *pb.GetProductRequest) (*pb.ProductResponse, error) {
-	mu.Lock()
-	defer mu.Unlock()
+// newServer returns a server that keeps its products and orders in st
+func newServer(st store.Store, cfg config) *server {
+	if cfg.eventRetention <= 0 {
+		cfg.eventRetention = defaultEventRetention
+	}
+	if cfg.idempotencyWindow <= 0 {
+		cfg.idempotencyWindow = defaultIdempotencyWindow
+	}
+	if cfg.currency == "" {
+		cfg.currency = defaultCurrency
+	}
+	if cfg.defaultWarehouse == "" {
+		cfg.defaultWarehouse = defaultWarehouse
+	}
+	if cfg.holdTTL <= 0 {
+		cfg.holdTTL = defaultHoldTTL
+	}
+	var n notifier = logNotifier{}
+	if cfg.alertWebhook != "" {
+		n = newWebhookNotifier(cfg.alertWebhook)
+	}
+	return &server{config: cfg, store: st, events: newEventHub(), notifier: n}
+}
 
-	product, exists := productStore[req.ProductId]
-	if !exists {
-		return &pb.ProductResponse{Status: "error", Message: "Product not found"}, nil
+// GetProduct fetches a product by its ID along with its stock per warehouse
+func (s *server) GetProduct(ctx context.Context, req *pb.GetProductRequest) (*pb.ProductResponse, error) {
+	var product *pb.Product
+	err := s.store.View(ctx, func(tx store.Tx) error {
+		var err error
+		product, err = s.getProduct(tx, req.ProductId)
+		if err != nil {
+			return err
+		}
+		if product.StockLevels, err = s.stockLevels(tx, product); err != nil {
+			return err
+		}
+		product.Lots, err = productLots(tx, product.Id)
+		return err
+	})
+	if err != nil {
+		return s.productError(storeError(err))
 	}
 	return &pb.ProductResponse{Product: product, Status: "success"}, nil
 }
 
-// UpdateProduct updates an existing product
-func (s *server) UpdateProduct(ctx context.Context, req *pb.UpdateProductRequest) (*pb.ProductResponse, error) {
-	mu.Lock()
-	defer mu.Unlock()
+// getProduct loads a product, reporting a missing one as NotFound. Float
+// prices stored before price_money existed are converted.
+func (s *server) getProduct(tx store.Tx, id string) (*pb.Product, error) {
+	product, err := tx.Products().Get(id)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, notFound(productResource, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	normalizeProductPrice(product, s.currency)
+	return product, nil
+}
+
+// validateProduct checks the client-supplied fields of a product being
+// created or updated
+func validateProduct(p *pb.Product) error {
+	if p.PriceMoney != nil {
+		if err := validateMoney("product.price_money", p.PriceMoney); err != nil {
+			return err
+		}
+	} else if err := validatePrice(p.Price); err != nil {
+		return err
+	}
+	switch {
+	case p.ReorderPoint < 0:
+		return invalidArgument("product.reorder_point", "must not be negative")
+	case p.ReorderQuantity < 0:
+		return invalidArgument("product.reorder_quantity", "must not be negative")
+	}
+	return nil
+}
+
+// validatePrice checks a legacy float price the way price_money would be
+// checked once it is converted
+func validatePrice(price float32) error {
+	m, err := moneyFromFloat(price, defaultCurrency)
+	if err != nil {
+		return invalidArgument("product.price", err.Error())
+	}
+	return validateMoney("product.price", m)
+}
+
+// checkVersion fails with Aborted if the caller expects a version other
+// than the stored one. An expected version of 0 skips the check.
+func checkVersion(resourceType, name string, expected, stored int64) error {
+	if expected != 0 && expected != stored {
+		return versionMismatch(resourceType, name, expected, stored)
+	}
+	return nil
+}
 
-	productStore[req.Product.Id] = req.Product
-	return &pb.ProductResponse{Product: req.Product, Status: "success", Message: "Product updated"}, nil
+// newID returns a time-ordered UUIDv7 so generated IDs sort by creation time
+func newID() (string, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return "", err
+	}
+	return id.String(), nil
+}
+
+// CreateProduct adds a new product, generating its ID when none is supplied
+func (s *server) CreateProduct(ctx context.Context, req *pb.CreateProductRequest) (*pb.ProductResponse, error) {
+	if req.Product == nil {
+		return s.productError(invalidArgument("product", "is required"))
+	}
+	if err := validateProduct(req.Product); err != nil {
+		return s.productError(err)
+	}
+	if req.Product.Serialized && req.Product.InventoryLevel != 0 {
+		return s.productError(invalidArgument("product.inventory_level",
+			"must be 0 for a serialized product; its units are added with ReceiveSerials"))
+	}
+	product := proto.Clone(req.Product).(*pb.Product)
+	normalizeProductPrice(product, s.currency)
+	product.StockLevels, product.Lots = nil, nil
+	if product.Id == "" {
+		id, err := newID()
+		if err != nil {
+			return s.productError(status.Errorf(codes.Internal, "failed to generate product id: %v", err))
+		}
+		product.Id = id
+	}
+	product.Version = 1
+
+	err := s.update(ctx, func(tx store.Tx) error {
+		err := tx.Products().Create(product)
+		if errors.Is(err, store.ErrAlreadyExists) {
+			return alreadyExists(productResource, product.Id)
+		}
+		if err != nil {
+			return err
+		}
+		if product.InventoryLevel != 0 {
+			reason := pb.StockMovementReason_RECEIVED
+			if product.InventoryLevel < 0 {
+				reason = pb.StockMovementReason_COUNT_CORRECTION
+			}
+			err := appendMovement(tx, &pb.StockMovement{
+				ProductId:     product.Id,
+				WarehouseId:   s.defaultWarehouse,
+				Delta:         product.InventoryLevel,
+				Reason:        reason,
+				QuantityAfter: product.InventoryLevel,
+				Note:          "initial stock",
+				Cause:         "CreateProduct",
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return s.recordStockEvent(tx, nil, product, "CreateProduct")
+	})
+	if err != nil {
+		return s.productError(storeError(err))
+	}
+	return &pb.ProductResponse{Product: product, Status: "success", Message: "Product created"}, nil
+}
+
+// UpdateProduct updates an existing product, changing only the fields in
+// update_mask when one is given
+func (s *server) UpdateProduct(ctx context.Context, req *pb.UpdateProductRequest) (*pb.UpdateProductResponse, error) {
+	if req.Product == nil || req.Product.Id == "" {
+		return s.updateProductError(invalidArgument("product.id", "is required"))
+	}
+	if err := validateProduct(req.Product); err != nil {
+		return s.updateProductError(err)
+	}
+	paths, err := maskPaths(req.UpdateMask, req.Product, productMaskFields)
+	if err != nil {
+		return s.updateProductError(err)
+	}
+	key, err := idempotencyKey(ctx, req.IdempotencyKey)
+	if err != nil {
+		return s.updateProductError(err)
+	}
+
+	res := &pb.UpdateProductResponse{}
+	err = s.mutate(ctx, key, req, res, func(tx store.Tx) error {
+		existing, err := s.getProduct(tx, req.Product.Id)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(productResource, existing.Id, req.Product.Version, existing.Version); err != nil {
+			return err
+		}
+		product := proto.Clone(req.Product).(*pb.Product)
+		if paths != nil {
+			product = proto.Clone(existing).(*pb.Product)
+			applyMask(product, req.Product, paths)
+			if slices.Contains(paths, "price") && !slices.Contains(paths, "price_money") {
+				if err := validatePrice(product.Price); err != nil {
+					return err
+				}
+				product.PriceMoney = nil
+			}
+		}
+		normalizeProductPrice(product, s.currency)
+		product.StockLevels, product.Lots = nil, nil
+		if err := checkSerializedChange(tx, existing, product); err != nil {
+			return err
+		}
+		// inventory_level is the total; what other warehouses hold stays put
+		// and the default warehouse absorbs the change through the ledger.
+		current := existing
+		if delta := product.InventoryLevel - existing.InventoryLevel; delta != 0 {
+			if existing.Serialized {
+				return failedPrecondition("SERIALIZED", existing.Id, fmt.Sprintf(
+					"stock of serialized product %q changes with ReceiveSerials and AdjustStock", existing.Id))
+			}
+			current, err = s.moveStock(tx, existing, &pb.StockMovement{
+				WarehouseId: s.defaultWarehouse,
+				Delta:       delta,
+				Reason:      pb.StockMovementReason_COUNT_CORRECTION,
+				Cause:       "UpdateProduct",
+			})
+			if err != nil {
+				return err
+			}
+		}
+		product.InventoryLevel = current.InventoryLevel
+		product.Version = current.Version
+		if current == existing {
+			product.Version++
+		}
+		if err := tx.Products().Update(product); err != nil {
+			return err
+		}
+		if err := s.recordStockEvent(tx, current, product, "UpdateProduct"); err != nil {
+			return err
+		}
+		res.Product, res.Status, res.Message = product, "success", "Product updated"
+		return nil
+	})
+	if err != nil {
+		return s.updateProductError(storeError(err))
+	}
+	return res, nil
 }
 
 // DeleteProduct deletes a product by its ID
 func (s *server) DeleteProduct(ctx context.Context, req *pb.DeleteProductRequest) (*pb.DeleteProductResponse, error) {
-	mu.Lock()
-	defer mu.Unlock()
+	key, err := idempotencyKey(ctx, req.IdempotencyKey)
+	if err != nil {
+		return s.deleteProductError(err)
+	}
+
+	res := &pb.DeleteProductResponse{}
+	err = s.mutate(ctx, key, req, res, func(tx store.Tx) error {
+		// Orders hold stock reserved from the product, so it cannot go away
+		// while any of them remain.
+		orders, err := tx.Orders().ListByProduct(req.ProductId)
+		if err != nil {
+			return err
+		}
+		if len(orders) > 0 {
+			return failedPrecondition("REFERENCED", req.ProductId,
+				fmt.Sprintf("product %q is referenced by order %q", req.ProductId, orders[0].Id))
+		}
+		// Nor while a hold sets it aside or a transfer is carrying it.
+		hold, err := activeHoldOn(tx, req.ProductId, "")
+		if err != nil {
+			return err
+		}
+		if hold != nil {
+			return failedPrecondition("REFERENCED", req.ProductId,
+				fmt.Sprintf("product %q is held by hold %q", req.ProductId, hold.Id))
+		}
+		transfer, err := transferThrough(tx, req.ProductId, "")
+		if err != nil {
+			return err
+		}
+		if transfer != nil {
+			return failedPrecondition("REFERENCED", req.ProductId,
+				fmt.Sprintf("product %q is in transit on transfer %q", req.ProductId, transfer.Id))
+		}
+		existing, err := s.getProduct(tx, req.ProductId)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(productResource, existing.Id, req.ExpectedVersion, existing.Version); err != nil {
+			return err
+		}
+		lots, err := tx.Lots().ListByProduct(req.ProductId)
+		if err != nil {
+			return err
+		}
+		for _, lot := range lots {
+			if err := tx.Lots().Delete(lot.Id); err != nil {
+				return err
+			}
+		}
+		// Write the remaining stock off so the ledger still balances.
+		levels, err := s.stockLevels(tx, existing)
+		if err != nil {
+			return err
+		}
+		for _, level := range levels {
+			if level.WarehouseId != s.defaultWarehouse {
+				if err := tx.Stock().Delete(level.ProductId, level.WarehouseId); err != nil {
+					return err
+				}
+			}
+			if level.Quantity == 0 {
+				continue
+			}
+			err := appendMovement(tx, &pb.StockMovement{
+				ProductId:   level.ProductId,
+				WarehouseId: level.WarehouseId,
+				Delta:       -level.Quantity,
+				Reason:      pb.StockMovementReason_COUNT_CORRECTION,
+				Note:        "product deleted",
+				Cause:       "DeleteProduct",
+			})
+			if err != nil {
+				return err
+			}
+		}
+		if err := tx.Products().Delete(req.ProductId); err != nil {
+			return err
+		}
+		res.Success = true
+		return s.recordStockEvent(tx, existing, nil, "DeleteProduct")
+	})
+	if err != nil {
+		return s.deleteProductError(storeError(err))
+	}
+	return res, nil
+}
+
+// ListProducts returns a page of products matching the request filters
+func (s *server) ListProducts(ctx context.Context, req *pb.ListProductsRequest) (*pb.ListProductsResponse, error) {
+	size, err := pageSize(req.PageSize)
+	if err != nil {
+		return nil, invalidArgument("page_size", err.Error())
+	}
+	order, err := parseProductOrderBy(req.OrderBy)
+	if err != nil {
+		return nil, invalidArgument("order_by", err.Error())
+	}
+	var minPrice, maxPrice *pb.Money
+	if req.MinPrice != nil {
+		if minPrice, err = moneyFromFloat(req.GetMinPrice(), ""); err != nil {
+			return nil, invalidArgument("min_price", err.Error())
+		}
+	}
+	if req.MaxPrice != nil {
+		if maxPrice, err = moneyFromFloat(req.GetMaxPrice(), ""); err != nil {
+			return nil, invalidArgument("max_price", err.Error())
+		}
+	}
+	query := queryFingerprint(req.NameContains,
+		req.MinPrice != nil, req.GetMinPrice(), req.MaxPrice != nil, req.GetMaxPrice(),
+		req.MinInventoryLevel != nil, req.GetMinInventoryLevel(),
+		req.MaxInventoryLevel != nil, req.GetMaxInventoryLevel(),
+		order)
 
-	if _, exists := productStore[req.ProductId]; exists {
-		delete(productStore, req.ProductId)
-		return &pb.DeleteProductResponse{Success: true}, nil
+	var after *pb.Product
+	if req.PageToken != "" {
+		var cursor productCursor
+		if err := decodePageToken(req.PageToken, query, &cursor); err != nil {
+			return nil, invalidArgument("page_token", err.Error())
+		}
+		after = cursor.product()
 	}
-	return &pb.DeleteProductResponse{Success: false}, nil
+
+	var products []*pb.Product
+	err = s.store.View(ctx, func(tx store.Tx) error {
+		var err error
+		products, err = tx.Products().List()
+		return err
+	})
+	if err != nil {
+		return nil, storeError(err)
+	}
+	for _, product := range products {
+		normalizeProductPrice(product, s.currency)
+	}
+
+	var matches []*pb.Product
+	for _, product := range products {
+		if !matchesProductFilter(req, minPrice, maxPrice, product) {
+			continue
+		}
+		if after != nil && order.compare(product, after) <= 0 {
+			continue
+		}
+		matches = append(matches, product)
+	}
+
+	slices.SortFunc(matches, order.compare)
+	res := &pb.ListProductsResponse{}
+	if len(matches) > size {
+		matches = matches[:size]
+		res.NextPageToken, err = encodePageToken(query, newProductCursor(matches[size-1]))
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+	}
+	res.Products = matches
+	return res, nil
+}
+
+// stockClaim is what reserved stock is set aside for: an order or a cart
+// hold
+type stockClaim struct {
+	allocations        *[]*pb.LotAllocation
+	reserved, released pb.StockMovementReason
+	orderID, holdID    string
+}
+
+func orderClaim(order *pb.Order) stockClaim {
+	return stockClaim{
+		allocations: &order.LotAllocations,
+		reserved:    pb.StockMovementReason_ORDER_RESERVED,
+		released:    pb.StockMovementReason_ORDER_RELEASED,
+		orderID:     order.Id,
+	}
+}
+
+// reserveStock applies per-product stock changes for an order; see
+// claimStock
+func (s *server) reserveStock(tx store.Tx, order *pb.Order, warehouseID string, deltas map[string]int32, cause string) error {
+	return s.claimStock(tx, orderClaim(order), warehouseID, deltas, cause)
+}
+
+// claimStock applies per-product stock changes for a claim at one warehouse
+// inside tx. A positive delta takes units out of the warehouse and
+// inventory_level and a negative delta returns them; both are recorded in
+// the ledger against the claim. Units taken come from the warehouse's lots
+// first-expired-first-out and are recorded in the claim's lot allocations;
+// returned units go back to the lots they came from. Every product being
+// drawn from must exist and the warehouse must hold enough of it, otherwise
+// an error is returned and the transaction is expected to roll back. Stock
+// returned to a warehouse that has since been deleted goes to the default
+// warehouse. Products are visited in ID order so concurrent transactions
+// lock them in the same order. cause names the RPC in the resulting
+// inventory events.
+func (s *server) claimStock(tx store.Tx, claim stockClaim, warehouseID string, deltas map[string]int32, cause string) error {
+	if warehouseID != s.defaultWarehouse {
+		_, err := tx.Warehouses().Get(warehouseID)
+		if errors.Is(err, store.ErrNotFound) && !drawsStock(deltas) {
+			warehouseID = s.defaultWarehouse
+		} else if errors.Is(err, store.ErrNotFound) {
+			return notFound(warehouseResource, warehouseID)
+		} else if err != nil {
+			return err
+		}
+	}
+
+	ids := make([]string, 0, len(deltas))
+	for id := range deltas {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	for _, productID := range ids {
+		delta := deltas[productID]
+		if delta == 0 {
+			continue
+		}
+		product, err := tx.Products().Get(productID)
+		if errors.Is(err, store.ErrNotFound) && delta < 0 {
+			continue // nothing to return stock to
+		}
+		if errors.Is(err, store.ErrNotFound) {
+			return notFound(productResource, productID)
+		}
+		if err != nil {
+			return err
+		}
+		normalizeProductPrice(product, s.currency)
+		reason := claim.reserved
+		if delta > 0 {
+			available, err := s.allocatable(tx, product, warehouseID)
+			if err != nil {
+				return err
+			}
+			if available < delta {
+				return failedPrecondition("STOCK", productID, fmt.Sprintf(
+					"insufficient unexpired stock for product %q at warehouse %q: requested %d, available %d",
+					productID, warehouseID, delta, available))
+			}
+			err = allocateLots(tx, claim.allocations, productID, warehouseID, delta)
+		} else {
+			reason = claim.released
+			err = releaseLots(tx, claim.allocations, productID, warehouseID, -delta)
+		}
+		if err != nil {
+			return err
+		}
+		_, err = s.moveStock(tx, product, &pb.StockMovement{
+			WarehouseId: warehouseID,
+			Delta:       -delta,
+			Reason:      reason,
+			OrderId:     claim.orderID,
+			HoldId:      claim.holdID,
+			Cause:       cause,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// drawsStock reports whether any delta takes stock out
+func drawsStock(deltas map[string]int32) bool {
+	for _, delta := range deltas {
+		if delta > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// getOrder loads an order, reporting a missing one as NotFound
+func (s *server) getOrder(tx store.Tx, id string) (*pb.Order, error) {
+	order, err := tx.Orders().Get(id)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, notFound(orderResource, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.normalizeOrder(order)
+	return order, nil
 }
 
-// CreateOrder creates a new order
+// normalizeOrder converts orders stored before lines, money fields or
+// warehouses existed; the latter were all reserved from the default
+// warehouse.
+func (s *server) normalizeOrder(order *pb.Order) {
+	normalizeLines(order)
+	normalizeOrderPrices(order, s.currency)
+	if order.WarehouseId == "" {
+		order.WarehouseId = s.defaultWarehouse
+	}
+}
+
+// CreateOrder creates a new order, reserving every line's quantity from
+// stock at one warehouse. Lines that cannot be reserved in full are
+// rejected, backordered or cut according to the backorder policy.
 func (s *server) CreateOrder(ctx context.Context, req *pb.CreateOrderRequest) (*pb.OrderResponse, error) {
-	mu.Lock()
-	defer mu.Unlock()
+	if err := validateOrder(req.Order); err != nil {
+		return s.orderError(err)
+	}
+	if _, ok := pb.BackorderPolicy_name[int32(req.BackorderPolicy)]; !ok {
+		return s.orderError(invalidArgument("backorder_policy", "is not a known policy"))
+	}
+	key, err := idempotencyKey(ctx, req.IdempotencyKey)
+	if err != nil {
+		return s.orderError(err)
+	}
+	id := req.Order.Id
+	if id == "" {
+		if id, err = newID(); err != nil {
+			return s.orderError(status.Errorf(codes.Internal, "failed to generate order id: %v", err))
+		}
+	}
 
-	req.Order.OrderDate = timestamppb.Now()
-	orderStore[req.Order.Id] = req.Order
-	return &pb.OrderResponse{Order: req.Order, Status: "success", Message: "Order created"}, nil
+	res := &pb.OrderResponse{}
+	err = s.mutate(ctx, key, req, res, func(tx store.Tx) error {
+		// Backordering cuts lines, so every attempt starts from the request.
+		order := proto.Clone(req.Order).(*pb.Order)
+		normalizeLines(order)
+		order.Id, order.BackorderPolicy = id, req.BackorderPolicy
+		order.LotAllocations = nil
+		deltas := lineDeltas(order, false)
+		if order.WarehouseId == "" {
+			var err error
+			if order.WarehouseId, err = s.fulfillmentWarehouse(tx, deltas); err != nil {
+				return err
+			}
+		} else if _, err := getWarehouse(tx, order.WarehouseId); err != nil {
+			return err
+		}
+		if err := s.backorderLines(tx, order, nil); err != nil {
+			return err
+		}
+		// Reserve first: it locks the products in ID order, which pricing
+		// does not.
+		if err := s.reserveStock(tx, order, order.WarehouseId, lineDeltas(order, false), "CreateOrder"); err != nil {
+			return err
+		}
+		if err := s.assignSerials(tx, order, nil, "CreateOrder"); err != nil {
+			return err
+		}
+		if err := s.priceOrder(tx, order, nil); err != nil {
+			return err
+		}
+		order.OrderDate = timestamppb.Now()
+		order.Version = 1
+		order.Status = pb.OrderStatus_ORDER_STATUS_PENDING
+		order.StatusHistory = nil
+		err := tx.Orders().Create(order)
+		if errors.Is(err, store.ErrAlreadyExists) {
+			return alreadyExists(orderResource, order.Id)
+		}
+		if err != nil {
+			return err
+		}
+		res.Order, res.Status, res.Message = order, "success", "Order created"
+		return nil
+	})
+	if err != nil {
+		return s.orderError(storeError(err))
+	}
+	return res, nil
 }
 
 // GetOrder fetches an order by its ID
 func (s *server) GetOrder(ctx context.Context, req *pb.GetOrderRequest) (*pb.OrderResponse, error) {
-	mu.Lock()
-	defer mu.Unlock()
-
-	order, exists := orderStore[req.OrderId]
-	if !exists {
-		return &pb.OrderResponse{Status: "error", Message: "Order not found"}, nil
+	var order *pb.Order
+	err := s.store.View(ctx, func(tx store.Tx) error {
+		var err error
+		order, err = s.getOrder(tx, req.OrderId)
+		return err
+	})
+	if err != nil {
+		return s.orderError(storeError(err))
 	}
 	return &pb.OrderResponse{Order: order, Status: "success"}, nil
 }
 
-// UpdateOrder updates an existing order
+// UpdateOrder updates an existing order, re-adjusting the stock it reserves.
+// Only the fields in update_mask are changed when one is given.
 func (s *server) UpdateOrder(ctx context.Context, req *pb.UpdateOrderRequest) (*pb.UpdateOrderResponse, error) {
-	mu.Lock()
-	defer mu.Unlock()
+	if req.Order == nil {
+		return s.updateOrderError(invalidArgument("order", "is required"))
+	}
+	paths, err := maskPaths(req.UpdateMask, req.Order, orderMaskFields)
+	if err != nil {
+		return s.updateOrderError(err)
+	}
+	if paths == nil {
+		if err := validateOrder(req.Order); err != nil {
+			return s.updateOrderError(err)
+		}
+	}
+	key, err := idempotencyKey(ctx, req.IdempotencyKey)
+	if err != nil {
+		return s.updateOrderError(err)
+	}
 
-	orderStore[req.Order.Id] = req.Order
-	return &pb.UpdateOrderResponse{Order: req.Order, Status: "success", Message: "Order updated"}, nil
+	res := &pb.UpdateOrderResponse{}
+	err = s.mutate(ctx, key, req, res, func(tx store.Tx) error {
+		existing, err := s.getOrder(tx, req.Order.Id)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(orderResource, existing.Id, req.Order.Version, existing.Version); err != nil {
+			return err
+		}
+		if !isEditable(existing) {
+			return failedPrecondition("ORDER_STATUS", existing.Id,
+				fmt.Sprintf("order %q is %s and can no longer be changed", existing.Id, orderStatus(existing)))
+		}
+		order := proto.Clone(req.Order).(*pb.Order)
+		if paths != nil {
+			order = mergeOrder(existing, req.Order, paths)
+			if err := validateOrder(order); err != nil {
+				return err
+			}
+		}
+		normalizeLines(order)
+		if order.WarehouseId == "" {
+			order.WarehouseId = existing.WarehouseId
+		}
+		order.LotAllocations = existing.LotAllocations
+		order.BackorderPolicy = existing.BackorderPolicy
+
+		if order.WarehouseId == existing.WarehouseId {
+			if err := s.backorderLines(tx, order, lineDeltas(existing, false)); err != nil {
+				return err
+			}
+			deltas := lineDeltas(existing, true)
+			for productID, quantity := range lineDeltas(order, false) {
+				deltas[productID] += quantity
+			}
+			if err := s.reserveStock(tx, order, order.WarehouseId, deltas, "UpdateOrder"); err != nil {
+				return err
+			}
+		} else {
+			if _, err := getWarehouse(tx, order.WarehouseId); err != nil {
+				return err
+			}
+			if err := s.reserveStock(tx, order, existing.WarehouseId, lineDeltas(existing, true), "UpdateOrder"); err != nil {
+				return err
+			}
+			if err := s.backorderLines(tx, order, nil); err != nil {
+				return err
+			}
+			if err := s.reserveStock(tx, order, order.WarehouseId, lineDeltas(order, false), "UpdateOrder"); err != nil {
+				return err
+			}
+		}
+		if err := s.assignSerials(tx, order, existing, "UpdateOrder"); err != nil {
+			return err
+		}
+		if err := s.priceOrder(tx, order, existing); err != nil {
+			return err
+		}
+		order.OrderDate = existing.OrderDate
+		order.Version = existing.Version + 1
+		order.Status = existing.Status
+		order.StatusHistory = existing.StatusHistory
+		res.Order, res.Status, res.Message = order, "success", "Order updated"
+		return tx.Orders().Update(order)
+	})
+	if err != nil {
+		return s.updateOrderError(storeError(err))
+	}
+	return res, nil
 }
 
-// DeleteOrder deletes an order by its ID
+// DeleteOrder deletes an order by its ID, returning its quantity to stock if
+// it is still reserved
 func (s *server) DeleteOrder(ctx context.Context, req *pb.DeleteOrderRequest) (*pb.DeleteOrderResponse, error) {
-	mu.Lock()
-	defer mu.Unlock()
+	key, err := idempotencyKey(ctx, req.IdempotencyKey)
+	if err != nil {
+		return s.deleteOrderError(err)
+	}
 
-	if _, exists := orderStore[req.OrderId]; exists {
-		delete(orderStore, req.OrderId)
-		return &pb.DeleteOrderResponse{Success: true}, nil
+	res := &pb.DeleteOrderResponse{}
+	err = s.mutate(ctx, key, req, res, func(tx store.Tx) error {
+		order, err := s.getOrder(tx, req.OrderId)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(orderResource, order.Id, req.ExpectedVersion, order.Version); err != nil {
+			return err
+		}
+		if holdsStock(order) {
+			if err := s.reserveStock(tx, order, order.WarehouseId, lineDeltas(order, true), "DeleteOrder"); err != nil {
+				return err
+			}
+			if err := releaseSerials(tx, order, "DeleteOrder"); err != nil {
+				return err
+			}
+		}
+		res.Success = true
+		return tx.Orders().Delete(order.Id)
+	})
+	if err != nil {
+		return s.deleteOrderError(storeError(err))
 	}
-	return &pb.DeleteOrderResponse{Success: false, Message: "Order not found"}, nil
+	return res, nil
 }
 
 func main() {
+	var cfg config
+	flag.BoolVar(&cfg.legacyStatus, "legacy-status", false,
+		"report errors in the response status/message fields instead of gRPC status codes")
+	flag.IntVar(&cfg.eventRetention, "event-retention", defaultEventRetention,
+		"number of inventory events kept for WatchInventory clients to resume from")
+	flag.DurationVar(&cfg.idempotencyWindow, "idempotency-window", defaultIdempotencyWindow,
+		"how long responses to requests with an idempotency key are kept for retries")
+	flag.StringVar(&cfg.currency, "currency", defaultCurrency,
+		"ISO 4217 currency code given to prices sent or stored as plain floats")
+	flag.StringVar(&cfg.defaultWarehouse, "default-warehouse", defaultWarehouse,
+		"ID of the warehouse holding stock not assigned to any other; created if missing")
+	flag.StringVar(&cfg.alertWebhook, "alert-webhook", "",
+		"URL that low-stock alerts are POSTed to as JSON; alerts are logged when empty")
+	flag.DurationVar(&cfg.holdTTL, "hold-ttl", defaultHoldTTL,
+		"how long a cart hold lasts when the request does not say")
+	var storage storageConfig
+	storage.registerFlags(flag.CommandLine)
+	flag.Parse()
+	if !currencyCode.MatchString(cfg.currency) {
+		log.Fatalf("Invalid -currency %q: want a three-letter ISO 4217 code", cfg.currency)
+	}
+
+	st, err := openStore(context.Background(), storage)
+	if err != nil {
+		log.Fatalf("Failed to open store: %v", err)
+	}
+	defer st.Close()
+
 	lis, err := net.Listen("tcp", ":50051")
 	if err != nil {
 		log.Fatalf("Failed to listen: %v", err)
 	}
 
+	srv := newServer(st, cfg)
+	if err := srv.ensureDefaultWarehouse(context.Background()); err != nil {
+		log.Fatalf("Failed to create default warehouse: %v", err)
+	}
+	if err := srv.reconcileLedger(context.Background()); err != nil {
+		log.Fatalf("Failed to reconcile stock ledger: %v", err)
+	}
 	grpcServer := grpc.NewServer()
-	pb.RegisterInventoryServiceServer(grpcServer, &server{})
+	pb.RegisterInventoryServiceServer(grpcServer, srv)
+
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	go srv.sweepIdempotencyKeys(workerCtx)
+	go srv.dispatchAlerts(workerCtx)
+	go srv.expireHolds(workerCtx)
+
+	// Drain in-flight requests before the deferred store Close on shutdown.
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-stop
+		grpcServer.GracefulStop()
+	}()
 
 	fmt.Println("gRPC server is running on port 50051...")
 	if err := grpcServer.Serve(lis); err != nil {
@@ -0,0 +1,441 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	pb "github.com/kingztech2019/proto_grpc/proto_inventory/grpc-inventory-proto"
+	"github.com/kingztech2019/proto_grpc/proto_inventory/store"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+// errSerialization stands in for the serialization failures the SQL store
+// retries
+var errSerialization = errors.New("serialization failure")
+
+// retryStore fails the commit of the next failures updates after their
+// function has run, then runs the function again on a fresh transaction,
+// the way the SQL store retries a transaction that lost a serialization
+// conflict. beforeRetry, if set, is the conflicting change, committed
+// before the retry.
+type retryStore struct {
+	store.Store
+	failures    int
+	beforeRetry func()
+}
+
+func (r *retryStore) Update(ctx context.Context, fn func(store.Tx) error) error {
+	for {
+		err := r.Store.Update(ctx, func(tx store.Tx) error {
+			if err := fn(tx); err != nil {
+				return err
+			}
+			if r.failures > 0 {
+				r.failures--
+				return errSerialization
+			}
+			return nil
+		})
+		if !errors.Is(err, errSerialization) {
+			return err
+		}
+		if r.beforeRetry != nil {
+			r.beforeRetry()
+		}
+	}
+}
+
+// newTestServer returns a server over st, or a new memory store when st is
+// nil, with the default warehouse in place
+func newTestServer(t *testing.T, st store.Store) *server {
+	t.Helper()
+	if st == nil {
+		st = store.NewMemory()
+	}
+	s := newServer(st, config{})
+	if err := s.ensureDefaultWarehouse(context.Background()); err != nil {
+		t.Fatalf("ensureDefaultWarehouse: %v", err)
+	}
+	return s
+}
+
+// usd returns a whole amount of US dollars
+func usd(units int64) *pb.Money {
+	return &pb.Money{CurrencyCode: "USD", Units: units}
+}
+
+// createProduct creates a product priced at $10 with level units in the
+// default warehouse
+func createProduct(t *testing.T, s *server, id string, level int32) {
+	t.Helper()
+	_, err := s.CreateProduct(context.Background(), &pb.CreateProductRequest{
+		Product: &pb.Product{Id: id, PriceMoney: usd(10), InventoryLevel: level},
+	})
+	if err != nil {
+		t.Fatalf("CreateProduct(%q): %v", id, err)
+	}
+}
+
+// createWarehouse creates a warehouse with the given ID
+func createWarehouse(t *testing.T, s *server, id string) {
+	t.Helper()
+	_, err := s.CreateWarehouse(context.Background(), &pb.CreateWarehouseRequest{Warehouse: &pb.Warehouse{Id: id, Name: id}})
+	if err != nil {
+		t.Fatalf("CreateWarehouse(%q): %v", id, err)
+	}
+}
+
+// receive adds n units of a product to the default warehouse at unitCost
+// dollars each, or with no cost when unitCost is 0
+func receive(t *testing.T, s *server, productID string, n int32, unitCost int64) {
+	t.Helper()
+	req := &pb.AdjustStockRequest{ProductId: productID, Delta: n, Reason: pb.StockMovementReason_RECEIVED}
+	if unitCost != 0 {
+		req.UnitCost = usd(unitCost)
+	}
+	if _, err := s.AdjustStock(context.Background(), req); err != nil {
+		t.Fatalf("AdjustStock(%q, %d): %v", productID, n, err)
+	}
+}
+
+// line returns an order line
+func line(productID string, quantity int32) *pb.OrderLine {
+	return &pb.OrderLine{ProductId: productID, Quantity: quantity}
+}
+
+// createOrder places an order for lines under policy
+func createOrder(t *testing.T, s *server, policy pb.BackorderPolicy, lines ...*pb.OrderLine) *pb.Order {
+	t.Helper()
+	res, err := s.CreateOrder(context.Background(), &pb.CreateOrderRequest{
+		Order:           &pb.Order{Lines: lines},
+		BackorderPolicy: policy,
+	})
+	if err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+	return res.Order
+}
+
+// transitionFunc is one of the order lifecycle RPCs
+type transitionFunc func(context.Context, *pb.OrderTransitionRequest) (*pb.OrderResponse, error)
+
+// advance moves an order through each transition in turn
+func advance(t *testing.T, orderID string, steps ...transitionFunc) {
+	t.Helper()
+	for _, step := range steps {
+		if _, err := step(context.Background(), &pb.OrderTransitionRequest{OrderId: orderID, Actor: "test"}); err != nil {
+			t.Fatalf("transition of order %q: %v", orderID, err)
+		}
+	}
+}
+
+// level returns a product's inventory_level
+func level(t *testing.T, s *server, productID string) int32 {
+	t.Helper()
+	res, err := s.GetProduct(context.Background(), &pb.GetProductRequest{ProductId: productID})
+	if err != nil {
+		t.Fatalf("GetProduct(%q): %v", productID, err)
+	}
+	return res.Product.InventoryLevel
+}
+
+// movements lists a product's ledger
+func movements(t *testing.T, s *server, productID string) []*pb.StockMovement {
+	t.Helper()
+	res, err := s.ListStockMovements(context.Background(), &pb.ListStockMovementsRequest{ProductId: productID, PageSize: 1000})
+	if err != nil {
+		t.Fatalf("ListStockMovements(%q): %v", productID, err)
+	}
+	return res.Movements
+}
+
+func wantCode(t *testing.T, err error, want codes.Code) {
+	t.Helper()
+	if got := status.Code(err); got != want {
+		t.Fatalf("error = %v, want code %s", err, want)
+	}
+}
+
+func TestCreateOrderReservesAllOrNothing(t *testing.T) {
+	s := newTestServer(t, nil)
+	createProduct(t, s, "a", 5)
+	createProduct(t, s, "b", 1)
+
+	_, err := s.CreateOrder(context.Background(), &pb.CreateOrderRequest{
+		Order: &pb.Order{Lines: []*pb.OrderLine{line("a", 3), line("b", 2)}},
+	})
+	wantCode(t, err, codes.FailedPrecondition)
+	if got := level(t, s, "a"); got != 5 {
+		t.Errorf("a after a rejected order = %d, want 5", got)
+	}
+
+	order := createOrder(t, s, pb.BackorderPolicy_BACKORDER_POLICY_UNSPECIFIED, line("a", 3), line("b", 1))
+	if a, b := level(t, s, "a"), level(t, s, "b"); a != 2 || b != 0 {
+		t.Errorf("levels after the order = %d, %d, want 2, 0", a, b)
+	}
+	if order.Status != pb.OrderStatus_ORDER_STATUS_PENDING || order.Version != 1 {
+		t.Errorf("order is %s at version %d, want PENDING at 1", order.Status, order.Version)
+	}
+	if got := order.TotalMoney.GetUnits(); got != 40 {
+		t.Errorf("total = %d, want 40", got)
+	}
+}
+
+func TestUpdateAndDeleteOrderMoveStock(t *testing.T) {
+	s := newTestServer(t, nil)
+	ctx := context.Background()
+	createProduct(t, s, "a", 10)
+	order := createOrder(t, s, 0, line("a", 4))
+
+	order.Lines = []*pb.OrderLine{line("a", 6)}
+	if _, err := s.UpdateOrder(ctx, &pb.UpdateOrderRequest{Order: order}); err != nil {
+		t.Fatalf("UpdateOrder: %v", err)
+	}
+	if got := level(t, s, "a"); got != 4 {
+		t.Errorf("level after growing the order = %d, want 4", got)
+	}
+	order.Lines = []*pb.OrderLine{line("a", 20)}
+	_, err := s.UpdateOrder(ctx, &pb.UpdateOrderRequest{Order: order})
+	wantCode(t, err, codes.Aborted) // the version moved on
+	order.Version = 2
+	_, err = s.UpdateOrder(ctx, &pb.UpdateOrderRequest{Order: order})
+	wantCode(t, err, codes.FailedPrecondition)
+
+	if _, err := s.DeleteOrder(ctx, &pb.DeleteOrderRequest{OrderId: order.Id}); err != nil {
+		t.Fatalf("DeleteOrder: %v", err)
+	}
+	if got := level(t, s, "a"); got != 10 {
+		t.Errorf("level after deleting the order = %d, want 10", got)
+	}
+}
+
+func TestOrderLifecycle(t *testing.T) {
+	s := newTestServer(t, nil)
+	ctx := context.Background()
+	createProduct(t, s, "a", 10)
+
+	order := createOrder(t, s, 0, line("a", 3))
+	_, err := s.ShipOrder(ctx, &pb.OrderTransitionRequest{OrderId: order.Id, Actor: "test"})
+	wantCode(t, err, codes.FailedPrecondition)
+	_, err = s.ConfirmOrder(ctx, &pb.OrderTransitionRequest{OrderId: order.Id})
+	wantCode(t, err, codes.InvalidArgument)
+
+	advance(t, order.Id, s.ConfirmOrder, s.StartPicking, s.ShipOrder)
+	if got := level(t, s, "a"); got != 7 {
+		t.Errorf("level after shipping = %d, want 7", got)
+	}
+	ledger := movements(t, s, "a")
+	if last := ledger[len(ledger)-1]; last.Reason != pb.StockMovementReason_ORDER_SHIPPED || last.Delta != 0 || last.OrderId != order.Id {
+		t.Errorf("last movement = %v, want an ORDER_SHIPPED of order %q with no delta", last, order.Id)
+	}
+	advance(t, order.Id, s.DeliverOrder, s.ReturnOrder)
+	if got := level(t, s, "a"); got != 10 {
+		t.Errorf("level after the return = %d, want 10", got)
+	}
+	res, err := s.GetOrder(ctx, &pb.GetOrderRequest{OrderId: order.Id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(res.Order.StatusHistory); res.Order.Status != pb.OrderStatus_ORDER_STATUS_RETURNED || n != 5 {
+		t.Errorf("order is %s with %d status changes, want RETURNED with 5", res.Order.Status, n)
+	}
+
+	cancelled := createOrder(t, s, 0, line("a", 4))
+	advance(t, cancelled.Id, s.ConfirmOrder, s.CancelOrder)
+	if got := level(t, s, "a"); got != 10 {
+		t.Errorf("level after cancelling = %d, want 10", got)
+	}
+	_, err = s.ConfirmOrder(ctx, &pb.OrderTransitionRequest{OrderId: cancelled.Id, Actor: "test"})
+	wantCode(t, err, codes.FailedPrecondition)
+}
+
+func TestSerializedStockNeedsSerials(t *testing.T) {
+	s := newTestServer(t, nil)
+	ctx := context.Background()
+
+	_, err := s.CreateProduct(ctx, &pb.CreateProductRequest{
+		Product: &pb.Product{Id: "s", PriceMoney: usd(10), Serialized: true, InventoryLevel: 2},
+	})
+	wantCode(t, err, codes.InvalidArgument)
+
+	createProduct(t, s, "a", 2)
+	_, err = s.UpdateProduct(ctx, &pb.UpdateProductRequest{
+		Product:    &pb.Product{Id: "a", Serialized: true},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"serialized"}},
+	})
+	wantCode(t, err, codes.FailedPrecondition)
+
+	createProduct(t, s, "s", 0)
+	_, err = s.UpdateProduct(ctx, &pb.UpdateProductRequest{
+		Product:    &pb.Product{Id: "s", Serialized: true},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"serialized"}},
+	})
+	if err != nil {
+		t.Fatalf("UpdateProduct(serialized) with no stock: %v", err)
+	}
+	_, err = s.ReceiveLot(ctx, &pb.ReceiveLotRequest{Lot: &pb.Lot{ProductId: "s", LotNumber: "L1", Quantity: 1}})
+	wantCode(t, err, codes.FailedPrecondition)
+	_, err = s.AdjustStock(ctx, &pb.AdjustStockRequest{ProductId: "s", Delta: 1, Reason: pb.StockMovementReason_RECEIVED})
+	wantCode(t, err, codes.FailedPrecondition)
+	_, err = s.ReceiveSerials(ctx, &pb.ReceiveSerialsRequest{ProductId: "s", SerialNumbers: []string{"s1", "s2"}})
+	if err != nil {
+		t.Fatalf("ReceiveSerials: %v", err)
+	}
+	_, err = s.AdjustStock(ctx, &pb.AdjustStockRequest{ProductId: "s", Delta: -1, Reason: pb.StockMovementReason_DAMAGED})
+	wantCode(t, err, codes.FailedPrecondition)
+	_, err = s.AdjustStock(ctx, &pb.AdjustStockRequest{
+		ProductId: "s", Delta: -1, Reason: pb.StockMovementReason_DAMAGED, SerialNumbers: []string{"s2"},
+	})
+	if err != nil {
+		t.Fatalf("AdjustStock with a serial number: %v", err)
+	}
+	sn, err := s.GetSerialNumber(ctx, &pb.GetSerialNumberRequest{ProductId: "s", SerialNumber: "s2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sn.Status != pb.SerialStatus_SERIAL_STATUS_WRITTEN_OFF {
+		t.Errorf("s2 is %s, want WRITTEN_OFF", sn.Status)
+	}
+	if got := level(t, s, "s"); got != 1 {
+		t.Errorf("level = %d, want 1", got)
+	}
+}
+
+func TestIdempotentCreateOrder(t *testing.T) {
+	s := newTestServer(t, nil)
+	ctx := context.Background()
+	createProduct(t, s, "a", 10)
+
+	req := &pb.CreateOrderRequest{Order: &pb.Order{Lines: []*pb.OrderLine{line("a", 2)}}, IdempotencyKey: "k"}
+	first, err := s.CreateOrder(ctx, req)
+	if err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+	again, err := s.CreateOrder(ctx, req)
+	if err != nil {
+		t.Fatalf("CreateOrder retry: %v", err)
+	}
+	if !proto.Equal(first, again) {
+		t.Errorf("retry got %v, want the first response %v", again, first)
+	}
+	if got := level(t, s, "a"); got != 8 {
+		t.Errorf("level after a retried order = %d, want 8", got)
+	}
+
+	other := &pb.CreateOrderRequest{Order: &pb.Order{Lines: []*pb.OrderLine{line("a", 3)}}, IdempotencyKey: "k"}
+	_, err = s.CreateOrder(ctx, other)
+	wantCode(t, err, codes.InvalidArgument)
+}
+
+// TestRetriedTransactions runs requests whose first commit fails because
+// two more units of a arrived meanwhile, checking that the retry gives the
+// same result as running the request once after they arrived
+func TestRetriedTransactions(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, s *server) proto.Message
+	}{{
+		name: "partial order",
+		run: func(t *testing.T, s *server) proto.Message {
+			return createOrder(t, s, pb.BackorderPolicy_BACKORDER_POLICY_PARTIAL, line("a", 6), line("b", 2))
+		},
+	}, {
+		name: "backordered order",
+		run: func(t *testing.T, s *server) proto.Message {
+			return createOrder(t, s, pb.BackorderPolicy_BACKORDER_POLICY_BACKORDER, line("a", 6), line("b", 2))
+		},
+	}, {
+		name: "order with a key",
+		run: func(t *testing.T, s *server) proto.Message {
+			res, err := s.CreateOrder(context.Background(), &pb.CreateOrderRequest{
+				Order:          &pb.Order{Id: "o", Lines: []*pb.OrderLine{line("a", 2)}},
+				IdempotencyKey: "k",
+			})
+			if err != nil {
+				t.Fatalf("CreateOrder: %v", err)
+			}
+			return res
+		},
+	}, {
+		name: "transfer of lots",
+		run: func(t *testing.T, s *server) proto.Message {
+			transfer, err := s.TransferStock(context.Background(), &pb.TransferStockRequest{Transfer: &pb.Transfer{
+				Id:                     "t",
+				SourceWarehouseId:      defaultWarehouse,
+				DestinationWarehouseId: "east",
+				Lines:                  []*pb.TransferLine{{ProductId: "a", Quantity: 3}},
+			}})
+			if err != nil {
+				t.Fatalf("TransferStock: %v", err)
+			}
+			return transfer
+		},
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var results [2]proto.Message
+			var levels [2][2]int32
+			for i, failures := range []int{0, 1} {
+				st := &retryStore{Store: store.NewMemory()}
+				s := newTestServer(t, st)
+				createWarehouse(t, s, "east")
+				createProduct(t, s, "a", 0)
+				createProduct(t, s, "b", 0)
+				_, err := s.ReceiveLot(context.Background(), &pb.ReceiveLotRequest{
+					Lot: &pb.Lot{ProductId: "a", LotNumber: "L1", Quantity: 4},
+				})
+				if err != nil {
+					t.Fatalf("ReceiveLot: %v", err)
+				}
+
+				arrive := func() { receive(t, s, "a", 2, 0) }
+				if failures == 0 {
+					arrive()
+				}
+				st.failures, st.beforeRetry = failures, arrive
+				results[i] = tt.run(t, s)
+				if st.failures != 0 {
+					t.Fatal("the request never committed")
+				}
+				levels[i] = [2]int32{level(t, s, "a"), level(t, s, "b")}
+			}
+			once, retried := clearTimes(results[0]), clearTimes(results[1])
+			if !proto.Equal(once, retried) {
+				t.Errorf("retried request got\n%v\nwant\n%v", retried, once)
+			}
+			if levels[0] != levels[1] {
+				t.Errorf("levels after a retry = %v, want %v", levels[1], levels[0])
+			}
+		})
+	}
+}
+
+// clearTimes returns m without the server-set times and generated IDs that
+// differ from one run to the next
+func clearTimes(m proto.Message) proto.Message {
+	m = proto.Clone(m)
+	var order *pb.Order
+	switch m := m.(type) {
+	case *pb.Order:
+		order = m
+	case *pb.OrderResponse:
+		order = m.Order
+	case *pb.Transfer:
+		m.CreateTime, m.UpdateTime = nil, nil
+		for _, a := range m.LotAllocations {
+			a.LotId = ""
+		}
+	}
+	if order != nil {
+		order.Id, order.OrderDate = "", nil
+		for _, a := range order.LotAllocations {
+			a.LotId = ""
+		}
+	}
+	return m
+}
@@ -0,0 +1,399 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	pb "github.com/kingztech2019/proto_grpc/proto_inventory/grpc-inventory-proto"
+	"github.com/kingztech2019/proto_grpc/proto_inventory/store"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// validateTransferLines checks that lines name each product once with a
+// positive quantity and at most one serial number per unit
+func validateTransferLines(field string, lines []*pb.TransferLine) error {
+	seen := make(map[string]bool, len(lines))
+	for i, line := range lines {
+		name := fmt.Sprintf("%s[%d]", field, i)
+		switch {
+		case line == nil:
+			return invalidArgument(name, "is required")
+		case line.ProductId == "":
+			return invalidArgument(name+".product_id", "is required")
+		case line.Quantity <= 0:
+			return invalidArgument(name+".quantity", "must be positive")
+		case seen[line.ProductId]:
+			return invalidArgument(name+".product_id", fmt.Sprintf("product %q is listed twice", line.ProductId))
+		case len(line.SerialNumbers) > int(line.Quantity):
+			return invalidArgument(name+".serial_numbers", "must not list more serial numbers than the quantity")
+		}
+		if err := validateSerialNumbers(name+".serial_numbers", line.SerialNumbers); err != nil {
+			return err
+		}
+		seen[line.ProductId] = true
+	}
+	return nil
+}
+
+// sortedLines returns lines ordered by product ID, the order stock is
+// locked in
+func sortedLines(lines []*pb.TransferLine) []*pb.TransferLine {
+	sorted := slices.Clone(lines)
+	slices.SortFunc(sorted, func(a, b *pb.TransferLine) int { return strings.Compare(a.ProductId, b.ProductId) })
+	return sorted
+}
+
+// getTransfer loads a transfer, reporting a missing one as NotFound
+func getTransfer(tx store.Tx, id string) (*pb.Transfer, error) {
+	transfer, err := tx.Transfers().Get(id)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, notFound(transferResource, id)
+	}
+	return transfer, err
+}
+
+// moveTransferStock moves units of a product into or out of a warehouse on
+// behalf of a transfer. Units arriving for a product deleted while they
+// were in transit are dropped.
+func (s *server) moveTransferStock(tx store.Tx, transfer *pb.Transfer, productID, warehouseID string, delta int32, reason pb.StockMovementReason, cause string) error {
+	product, err := tx.Products().Get(productID)
+	if errors.Is(err, store.ErrNotFound) && delta > 0 {
+		return nil
+	}
+	if errors.Is(err, store.ErrNotFound) {
+		return notFound(productResource, productID)
+	}
+	if err != nil {
+		return err
+	}
+	normalizeProductPrice(product, s.currency)
+	_, err = s.moveStock(tx, product, &pb.StockMovement{
+		WarehouseId: warehouseID,
+		Delta:       delta,
+		Reason:      reason,
+		TransferId:  transfer.Id,
+		Cause:       cause,
+	})
+	return err
+}
+
+// transferThrough returns a transfer in transit that still carries units of
+// productID, or that starts or ends at warehouseID, or nil if there is
+// none. Empty filters are ignored.
+func transferThrough(tx store.Tx, productID, warehouseID string) (*pb.Transfer, error) {
+	transfers, err := tx.Transfers().ListInTransit()
+	if err != nil {
+		return nil, err
+	}
+	for _, transfer := range transfers {
+		if warehouseID != "" && (transfer.SourceWarehouseId == warehouseID || transfer.DestinationWarehouseId == warehouseID) ||
+			productID != "" && slices.ContainsFunc(transfer.Lines, func(l *pb.TransferLine) bool {
+				return l.ProductId == productID && l.ReceivedQuantity < l.Quantity
+			}) {
+			return transfer, nil
+		}
+	}
+	return nil, nil
+}
+
+// arriveSerials lands the serial numbers of n units of a transfer line
+// arriving at the destination: those listed in serials, or the first n
+// still in transit when none are listed
+func arriveSerials(tx store.Tx, transfer *pb.Transfer, line *pb.TransferLine, serials []string, n int32) error {
+	if len(line.SerialNumbers) == 0 {
+		if len(serials) > 0 {
+			return failedPrecondition("SERIAL_NUMBERS", transfer.Id, fmt.Sprintf(
+				"transfer %q carries no serial numbers of product %q", transfer.Id, line.ProductId))
+		}
+		return nil
+	}
+	if len(serials) == 0 {
+		outstanding, err := inTransitSerials(tx, transfer, line)
+		if err != nil {
+			return err
+		}
+		serials = outstanding[:min(int(n), len(outstanding))]
+	} else if int32(len(serials)) != n {
+		return failedPrecondition("SERIAL_NUMBERS", transfer.Id, fmt.Sprintf(
+			"%d units of product %q are arriving but %d serial numbers are listed", n, line.ProductId, len(serials)))
+	}
+	return landSerials(tx, transfer, line.ProductId, serials, transfer.DestinationWarehouseId, "ReceiveTransfer")
+}
+
+// inTransit fails unless the transfer is still in transit
+func inTransit(transfer *pb.Transfer) error {
+	if transfer.Status != pb.TransferStatus_TRANSFER_STATUS_IN_TRANSIT {
+		return failedPrecondition("TRANSFER_STATUS", transfer.Id,
+			fmt.Sprintf("transfer %q is %s", transfer.Id, transfer.Status))
+	}
+	return nil
+}
+
+// TransferStock creates a transfer and takes its lines out of the source
+// warehouse, first-expired-first-out from its lots. Either every line is
+// taken or the transfer is rejected.
+func (s *server) TransferStock(ctx context.Context, req *pb.TransferStockRequest) (*pb.Transfer, error) {
+	t := req.Transfer
+	switch {
+	case t == nil:
+		return nil, invalidArgument("transfer", "is required")
+	case t.SourceWarehouseId == "":
+		return nil, invalidArgument("transfer.source_warehouse_id", "is required")
+	case t.DestinationWarehouseId == "":
+		return nil, invalidArgument("transfer.destination_warehouse_id", "is required")
+	case t.SourceWarehouseId == t.DestinationWarehouseId:
+		return nil, invalidArgument("transfer.destination_warehouse_id", "must differ from the source")
+	case len(t.Lines) == 0:
+		return nil, invalidArgument("transfer.lines", "at least one line is required")
+	}
+	if err := validateTransferLines("transfer.lines", t.Lines); err != nil {
+		return nil, err
+	}
+	key, err := idempotencyKey(ctx, req.IdempotencyKey)
+	if err != nil {
+		return nil, err
+	}
+	transfer := proto.Clone(t).(*pb.Transfer)
+	if transfer.Id == "" {
+		id, err := newID()
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to generate transfer id: %v", err)
+		}
+		transfer.Id = id
+	}
+	for _, line := range transfer.Lines {
+		line.ReceivedQuantity = 0
+	}
+
+	res := &pb.Transfer{}
+	err = s.mutate(ctx, key, req, res, func(tx store.Tx) error {
+		transfer.LotAllocations = nil
+		for _, id := range []string{transfer.SourceWarehouseId, transfer.DestinationWarehouseId} {
+			if _, err := getWarehouse(tx, id); err != nil {
+				return err
+			}
+		}
+		for _, line := range sortedLines(transfer.Lines) {
+			product, err := s.getProduct(tx, line.ProductId)
+			if err != nil {
+				return err
+			}
+			if err := checkSerialCount(product, line.SerialNumbers, line.Quantity); err != nil {
+				return err
+			}
+			if err := shipSerials(tx, transfer, line.ProductId, line.SerialNumbers, "TransferStock"); err != nil {
+				return err
+			}
+			// The units leave in their lots, so the lots travel with them.
+			err = allocateLots(tx, &transfer.LotAllocations, line.ProductId, transfer.SourceWarehouseId, line.Quantity)
+			if err != nil {
+				return err
+			}
+			err = s.moveTransferStock(tx, transfer, line.ProductId, transfer.SourceWarehouseId,
+				-line.Quantity, pb.StockMovementReason_TRANSFER_OUT, "TransferStock")
+			if err != nil {
+				return err
+			}
+		}
+		transfer.Status = pb.TransferStatus_TRANSFER_STATUS_IN_TRANSIT
+		transfer.Version = 1
+		transfer.CreateTime = timestamppb.Now()
+		transfer.UpdateTime = transfer.CreateTime
+		err := tx.Transfers().Create(transfer)
+		if errors.Is(err, store.ErrAlreadyExists) {
+			return alreadyExists(transferResource, transfer.Id)
+		}
+		if err != nil {
+			return err
+		}
+		proto.Reset(res)
+		proto.Merge(res, transfer)
+		return nil
+	})
+	if err != nil {
+		return nil, storeError(err)
+	}
+	return res, nil
+}
+
+// GetTransfer fetches a transfer by its ID
+func (s *server) GetTransfer(ctx context.Context, req *pb.GetTransferRequest) (*pb.Transfer, error) {
+	var transfer *pb.Transfer
+	err := s.store.View(ctx, func(tx store.Tx) error {
+		var err error
+		transfer, err = getTransfer(tx, req.TransferId)
+		return err
+	})
+	if err != nil {
+		return nil, storeError(err)
+	}
+	return transfer, nil
+}
+
+// ReceiveTransfer credits arriving units to the transfer's destination,
+// in the lots they left the source in
+func (s *server) ReceiveTransfer(ctx context.Context, req *pb.ReceiveTransferRequest) (*pb.Transfer, error) {
+	if req.TransferId == "" {
+		return nil, invalidArgument("transfer_id", "is required")
+	}
+	if err := validateTransferLines("lines", req.Lines); err != nil {
+		return nil, err
+	}
+	key, err := idempotencyKey(ctx, req.IdempotencyKey)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &pb.Transfer{}
+	err = s.mutate(ctx, key, req, res, func(tx store.Tx) error {
+		transfer, err := getTransfer(tx, req.TransferId)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(transferResource, transfer.Id, req.ExpectedVersion, transfer.Version); err != nil {
+			return err
+		}
+		if err := inTransit(transfer); err != nil {
+			return err
+		}
+		if _, err := getWarehouse(tx, transfer.DestinationWarehouseId); err != nil {
+			return err
+		}
+
+		arriving := make(map[string]int32, len(transfer.Lines))
+		serials := make(map[string][]string, len(req.Lines))
+		for _, line := range req.Lines {
+			serials[line.ProductId] = line.SerialNumbers
+		}
+		for _, line := range transfer.Lines {
+			arriving[line.ProductId] = line.Quantity - line.ReceivedQuantity
+		}
+		if len(req.Lines) > 0 {
+			outstanding := arriving
+			arriving = make(map[string]int32, len(req.Lines))
+			for i, line := range req.Lines {
+				left, ok := outstanding[line.ProductId]
+				switch {
+				case !ok:
+					return invalidArgument(fmt.Sprintf("lines[%d].product_id", i),
+						fmt.Sprintf("product %q is not part of the transfer", line.ProductId))
+				case line.Quantity > left:
+					return failedPrecondition("TRANSFER_QUANTITY", transfer.Id,
+						fmt.Sprintf("only %d units of product %q are outstanding", left, line.ProductId))
+				}
+				arriving[line.ProductId] = line.Quantity
+			}
+		}
+
+		complete := true
+		for _, line := range sortedLines(transfer.Lines) {
+			if n := arriving[line.ProductId]; n > 0 {
+				if err := arriveSerials(tx, transfer, line, serials[line.ProductId], n); err != nil {
+					return err
+				}
+				// Lots go in first so backorders filled by the arrival draw
+				// from them.
+				err := depositLots(tx, &transfer.LotAllocations, line.ProductId, transfer.DestinationWarehouseId, n)
+				if err != nil {
+					return err
+				}
+				err = s.moveTransferStock(tx, transfer, line.ProductId, transfer.DestinationWarehouseId,
+					n, pb.StockMovementReason_TRANSFER_IN, "ReceiveTransfer")
+				if err != nil {
+					return err
+				}
+				line.ReceivedQuantity += n
+			}
+			complete = complete && line.ReceivedQuantity == line.Quantity
+		}
+		if complete {
+			transfer.Status = pb.TransferStatus_TRANSFER_STATUS_RECEIVED
+		}
+		transfer.Version++
+		transfer.UpdateTime = timestamppb.Now()
+		if err := tx.Transfers().Update(transfer); err != nil {
+			return err
+		}
+		proto.Reset(res)
+		proto.Merge(res, transfer)
+		return nil
+	})
+	if err != nil {
+		return nil, storeError(err)
+	}
+	return res, nil
+}
+
+// CancelTransfer stops a transfer in transit and returns the units not yet
+// received to the source and its lots, or to the default warehouse if the
+// source has since been deleted
+func (s *server) CancelTransfer(ctx context.Context, req *pb.CancelTransferRequest) (*pb.Transfer, error) {
+	if req.TransferId == "" {
+		return nil, invalidArgument("transfer_id", "is required")
+	}
+	key, err := idempotencyKey(ctx, req.IdempotencyKey)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &pb.Transfer{}
+	err = s.mutate(ctx, key, req, res, func(tx store.Tx) error {
+		transfer, err := getTransfer(tx, req.TransferId)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(transferResource, transfer.Id, req.ExpectedVersion, transfer.Version); err != nil {
+			return err
+		}
+		if err := inTransit(transfer); err != nil {
+			return err
+		}
+		source := transfer.SourceWarehouseId
+		_, err = tx.Warehouses().Get(source)
+		if errors.Is(err, store.ErrNotFound) {
+			source = s.defaultWarehouse
+		} else if err != nil {
+			return err
+		}
+
+		for _, line := range sortedLines(transfer.Lines) {
+			if n := line.Quantity - line.ReceivedQuantity; n > 0 {
+				outstanding, err := inTransitSerials(tx, transfer, line)
+				if err != nil {
+					return err
+				}
+				if err := landSerials(tx, transfer, line.ProductId, outstanding, source, "CancelTransfer"); err != nil {
+					return err
+				}
+				err = depositLots(tx, &transfer.LotAllocations, line.ProductId, source, n)
+				if err != nil {
+					return err
+				}
+				err = s.moveTransferStock(tx, transfer, line.ProductId, source,
+					n, pb.StockMovementReason_TRANSFER_REVERSED, "CancelTransfer")
+				if err != nil {
+					return err
+				}
+			}
+		}
+		transfer.Status = pb.TransferStatus_TRANSFER_STATUS_CANCELLED
+		transfer.Version++
+		transfer.UpdateTime = timestamppb.Now()
+		if err := tx.Transfers().Update(transfer); err != nil {
+			return err
+		}
+		proto.Reset(res)
+		proto.Merge(res, transfer)
+		return nil
+	})
+	if err != nil {
+		return nil, storeError(err)
+	}
+	return res, nil
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	pb "github.com/kingztech2019/proto_grpc/proto_inventory/grpc-inventory-proto"
+)
+
+// lotQuantities maps a product's lot numbers at a warehouse to their
+// quantities
+func lotQuantities(t *testing.T, s *server, productID, warehouseID string) map[string]int32 {
+	t.Helper()
+	res, err := s.GetProduct(context.Background(), &pb.GetProductRequest{ProductId: productID})
+	if err != nil {
+		t.Fatalf("GetProduct(%q): %v", productID, err)
+	}
+	lots := make(map[string]int32)
+	for _, lot := range res.Product.Lots {
+		if lot.WarehouseId == warehouseID {
+			lots[lot.LotNumber] = lot.Quantity
+		}
+	}
+	return lots
+}
+
+// stockAtWarehouse returns a product's stock at one warehouse
+func stockAtWarehouse(t *testing.T, s *server, productID, warehouseID string) int32 {
+	t.Helper()
+	res, err := s.GetProduct(context.Background(), &pb.GetProductRequest{ProductId: productID})
+	if err != nil {
+		t.Fatalf("GetProduct(%q): %v", productID, err)
+	}
+	for _, level := range res.Product.StockLevels {
+		if level.WarehouseId == warehouseID {
+			return level.Quantity
+		}
+	}
+	return 0
+}
+
+func transferLine(productID string, quantity int32, serials ...string) *pb.TransferLine {
+	return &pb.TransferLine{ProductId: productID, Quantity: quantity, SerialNumbers: serials}
+}
+
+func transfer(t *testing.T, s *server, lines ...*pb.TransferLine) *pb.Transfer {
+	t.Helper()
+	res, err := s.TransferStock(context.Background(), &pb.TransferStockRequest{Transfer: &pb.Transfer{
+		SourceWarehouseId:      defaultWarehouse,
+		DestinationWarehouseId: "east",
+		Lines:                  lines,
+	}})
+	if err != nil {
+		t.Fatalf("TransferStock: %v", err)
+	}
+	return res
+}
+
+func TestTransferCarriesLots(t *testing.T) {
+	s := newTestServer(t, nil)
+	ctx := context.Background()
+	createWarehouse(t, s, "east")
+	createProduct(t, s, "a", 0)
+	_, err := s.ReceiveLot(ctx, &pb.ReceiveLotRequest{Lot: &pb.Lot{ProductId: "a", LotNumber: "L1", Quantity: 5}})
+	if err != nil {
+		t.Fatalf("ReceiveLot: %v", err)
+	}
+
+	received := transfer(t, s, transferLine("a", 3))
+	if n := len(received.LotAllocations); n != 1 || received.LotAllocations[0].Quantity != 3 {
+		t.Fatalf("lot allocations = %v, want 3 units of L1", received.LotAllocations)
+	}
+	if got := level(t, s, "a"); got != 2 {
+		t.Errorf("level in transit = %d, want 2", got)
+	}
+	_, err = s.ReceiveTransfer(ctx, &pb.ReceiveTransferRequest{TransferId: received.Id})
+	if err != nil {
+		t.Fatalf("ReceiveTransfer: %v", err)
+	}
+	if got := lotQuantities(t, s, "a", "east"); got["L1"] != 3 {
+		t.Errorf("lots at east = %v, want L1 x3", got)
+	}
+	if got := stockAtWarehouse(t, s, "a", "east"); got != 3 {
+		t.Errorf("stock at east = %d, want 3", got)
+	}
+
+	cancelled := transfer(t, s, transferLine("a", 2))
+	if got := lotQuantities(t, s, "a", defaultWarehouse); got["L1"] != 0 {
+		t.Errorf("lots at the source in transit = %v, want L1 empty", got)
+	}
+	_, err = s.CancelTransfer(ctx, &pb.CancelTransferRequest{TransferId: cancelled.Id})
+	if err != nil {
+		t.Fatalf("CancelTransfer: %v", err)
+	}
+	if got := lotQuantities(t, s, "a", defaultWarehouse); got["L1"] != 2 {
+		t.Errorf("lots at the source after cancelling = %v, want L1 x2", got)
+	}
+	if got := level(t, s, "a"); got != 5 {
+		t.Errorf("level = %d, want 5", got)
+	}
+}
+
+func TestTransferMovesSerials(t *testing.T) {
+	s := newTestServer(t, nil)
+	ctx := context.Background()
+	createWarehouse(t, s, "east")
+	_, err := s.CreateProduct(ctx, &pb.CreateProductRequest{
+		Product: &pb.Product{Id: "s", PriceMoney: usd(10), Serialized: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.ReceiveSerials(ctx, &pb.ReceiveSerialsRequest{ProductId: "s", SerialNumbers: []string{"s1", "s2", "s3"}})
+	if err != nil {
+		t.Fatalf("ReceiveSerials: %v", err)
+	}
+	serial := func(id string) *pb.SerialNumber {
+		t.Helper()
+		sn, err := s.GetSerialNumber(ctx, &pb.GetSerialNumberRequest{ProductId: "s", SerialNumber: id})
+		if err != nil {
+			t.Fatalf("GetSerialNumber(%q): %v", id, err)
+		}
+		return sn
+	}
+
+	_, err = s.TransferStock(ctx, &pb.TransferStockRequest{Transfer: &pb.Transfer{
+		SourceWarehouseId: defaultWarehouse, DestinationWarehouseId: "east",
+		Lines: []*pb.TransferLine{transferLine("s", 2, "s1")},
+	}})
+	if err == nil {
+		t.Fatal("TransferStock of 2 serialized units with 1 serial number succeeded")
+	}
+
+	tr := transfer(t, s, transferLine("s", 2, "s1", "s2"))
+	if sn := serial("s1"); sn.Status != pb.SerialStatus_SERIAL_STATUS_IN_TRANSIT || sn.TransferId != tr.Id {
+		t.Errorf("s1 is %s on transfer %q, want IN_TRANSIT on %q", sn.Status, sn.TransferId, tr.Id)
+	}
+	_, err = s.ReceiveTransfer(ctx, &pb.ReceiveTransferRequest{
+		TransferId: tr.Id,
+		Lines:      []*pb.TransferLine{transferLine("s", 1, "s2")},
+	})
+	if err != nil {
+		t.Fatalf("ReceiveTransfer: %v", err)
+	}
+	if sn := serial("s2"); sn.Status != pb.SerialStatus_SERIAL_STATUS_IN_STOCK || sn.WarehouseId != "east" {
+		t.Errorf("s2 is %s at %q, want IN_STOCK at east", sn.Status, sn.WarehouseId)
+	}
+	if _, err := s.CancelTransfer(ctx, &pb.CancelTransferRequest{TransferId: tr.Id}); err != nil {
+		t.Fatalf("CancelTransfer: %v", err)
+	}
+	if sn := serial("s1"); sn.Status != pb.SerialStatus_SERIAL_STATUS_IN_STOCK || sn.WarehouseId != defaultWarehouse {
+		t.Errorf("s1 is %s at %q, want IN_STOCK at the source", sn.Status, sn.WarehouseId)
+	}
+	if east, source := stockAtWarehouse(t, s, "s", "east"), stockAtWarehouse(t, s, "s", defaultWarehouse); east != 1 || source != 2 {
+		t.Errorf("stock at east and the source = %d, %d, want 1, 2", east, source)
+	}
+}
@@ -0,0 +1,367 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+
+	pb "github.com/kingztech2019/proto_grpc/proto_inventory/grpc-inventory-proto"
+	"github.com/kingztech2019/proto_grpc/proto_inventory/store"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// checkUnitCost validates the unit cost of a receipt of product, which
+// must be in the currency of the product's price
+func checkUnitCost(field string, cost *pb.Money, product *pb.Product) error {
+	if cost == nil {
+		return nil
+	}
+	if err := validateMoney(field, cost); err != nil {
+		return err
+	}
+	if cost.CurrencyCode != product.PriceMoney.CurrencyCode {
+		return invalidArgument(field+".currency_code", fmt.Sprintf(
+			"must be %s, the currency of product %q", product.PriceMoney.CurrencyCode, product.Id))
+	}
+	return nil
+}
+
+// transferKey prefixes the cost book key of units a transfer took out
+const transferKey = "transfer/"
+
+// costPiece is a number of units and what they cost in total, in nanos
+type costPiece struct {
+	quantity    int32
+	total       *big.Int
+	receiveTime *timestamppb.Timestamp
+}
+
+// split takes n of the piece's units, with their share of its cost
+func (p *costPiece) split(n int32) costPiece {
+	part := new(big.Int).Mul(p.total, big.NewInt(int64(n)))
+	part.Quo(part, big.NewInt(int64(p.quantity)))
+	p.quantity -= n
+	p.total.Sub(p.total, part)
+	return costPiece{quantity: n, total: part, receiveTime: p.receiveTime}
+}
+
+// costBook replays a product's ledger, keeping the cost of the stock on
+// hand under one method and the cost of units out on shipped orders and
+// transfers so they come back at the same cost
+type costBook struct {
+	method   pb.CostMethod
+	quantity int32
+	value    *big.Int
+	layers   []costPiece // oldest first; FIFO and LIFO only
+	taken    map[string][]costPiece
+}
+
+func newCostBook(method pb.CostMethod) *costBook {
+	return &costBook{method: method, value: new(big.Int), taken: make(map[string][]costPiece)}
+}
+
+// add puts units into stock
+func (b *costBook) add(p costPiece) {
+	if p.quantity <= 0 {
+		return
+	}
+	b.quantity += p.quantity
+	b.value.Add(b.value, p.total)
+	if b.method != pb.CostMethod_COST_METHOD_WEIGHTED_AVERAGE {
+		b.layers = append(b.layers, p)
+	}
+}
+
+// atAverage prices n units at the average cost of the stock on hand
+func (b *costBook) atAverage(n int32, at *timestamppb.Timestamp) costPiece {
+	total := new(big.Int)
+	if b.quantity > 0 {
+		total.Mul(b.value, big.NewInt(int64(n)))
+		total.Quo(total, big.NewInt(int64(b.quantity)))
+	}
+	return costPiece{quantity: n, total: total, receiveTime: at}
+}
+
+// take removes n units from stock in the order the method uses them.
+// Units beyond what the book holds cost nothing.
+func (b *costBook) take(n int32) []costPiece {
+	n = min(n, b.quantity)
+	if n <= 0 {
+		return nil
+	}
+	if b.method == pb.CostMethod_COST_METHOD_WEIGHTED_AVERAGE {
+		p := b.atAverage(n, nil)
+		b.quantity -= n
+		b.value.Sub(b.value, p.total)
+		return []costPiece{p}
+	}
+	var pieces []costPiece
+	for n > 0 {
+		i := 0
+		if b.method == pb.CostMethod_COST_METHOD_LIFO {
+			i = len(b.layers) - 1
+		}
+		layer := &b.layers[i]
+		p := layer.split(min(n, layer.quantity))
+		if layer.quantity == 0 {
+			b.layers = slices.Delete(b.layers, i, i+1)
+		}
+		b.quantity -= p.quantity
+		b.value.Sub(b.value, p.total)
+		pieces = append(pieces, p)
+		n -= p.quantity
+	}
+	return pieces
+}
+
+// giveBack hands back up to n of the units taken under key, most recently
+// taken first
+func (b *costBook) giveBack(key string, n int32) []costPiece {
+	pieces := b.taken[key]
+	var back []costPiece
+	for n > 0 && len(pieces) > 0 {
+		last := &pieces[len(pieces)-1]
+		p := last.split(min(n, last.quantity))
+		if last.quantity == 0 {
+			pieces = pieces[:len(pieces)-1]
+		}
+		back = append(back, p)
+		n -= p.quantity
+	}
+	b.taken[key] = pieces
+	return back
+}
+
+// sumPieces adds up the cost of pieces
+func sumPieces(pieces []costPiece) *big.Int {
+	total := new(big.Int)
+	for _, p := range pieces {
+		total.Add(total, p.total)
+	}
+	return total
+}
+
+// nanosToMoney converts an amount in nanos to Money
+func nanosToMoney(currency string, nanos *big.Int) (*pb.Money, error) {
+	units, rem := new(big.Int).QuoRem(nanos, big.NewInt(nanosPerUnit), new(big.Int))
+	if !units.IsInt64() {
+		return nil, status.Error(codes.OutOfRange, "amount overflows")
+	}
+	return &pb.Money{CurrencyCode: currency, Units: units.Int64(), Nanos: int32(rem.Int64())}, nil
+}
+
+// moneyToNanos converts Money to an amount in nanos
+func moneyToNanos(m *pb.Money) *big.Int {
+	nanos := new(big.Int).Mul(big.NewInt(m.Units), big.NewInt(nanosPerUnit))
+	return nanos.Add(nanos, big.NewInt(int64(m.Nanos)))
+}
+
+// valueProduct replays the product's ledger up to asOf. Units reserved by
+// orders and holds stay on hand until the order ships; then they are sold,
+// and count towards cost of goods sold if shipped after cogsFrom. Returns
+// give back the cost the units shipped at, less cost of goods sold if
+// returned after cogsFrom. Stock that transfers take out stays owned and
+// comes back at the cost it left at.
+func (s *server) valueProduct(tx store.Tx, product *pb.Product, method pb.CostMethod, asOf, cogsFrom time.Time) (*pb.ProductValuation, error) {
+	movements, err := tx.Movements().Query(store.MovementQuery{ProductID: product.Id})
+	if err != nil {
+		return nil, err
+	}
+	currency := product.PriceMoney.CurrencyCode
+	book := newCostBook(method)
+	cogs := new(big.Int)
+	held := make(map[string]int32)     // units set aside by each hold
+	reserved := make(map[string]int32) // units reserved by each order not yet shipped
+	shipped := make(map[string]bool)
+	for _, m := range movements {
+		at := m.Time.AsTime()
+		if at.After(asOf) {
+			break
+		}
+		var key string
+		sale := false
+		switch m.Reason {
+		case pb.StockMovementReason_HOLD_PLACED, pb.StockMovementReason_HOLD_RELEASED:
+			held[m.HoldId] -= m.Delta
+			continue
+		case pb.StockMovementReason_HOLD_CONVERTED:
+			reserved[m.OrderId] += held[m.HoldId]
+			delete(held, m.HoldId)
+			continue
+		case pb.StockMovementReason_ORDER_SHIPPED:
+			key = "order/" + m.OrderId
+			pieces := book.take(reserved[m.OrderId])
+			book.taken[key] = append(book.taken[key], pieces...)
+			if !at.Before(cogsFrom) {
+				cogs.Add(cogs, sumPieces(pieces))
+			}
+			delete(reserved, m.OrderId)
+			shipped[m.OrderId] = true
+			continue
+		case pb.StockMovementReason_ORDER_RESERVED, pb.StockMovementReason_ORDER_RELEASED:
+			if !shipped[m.OrderId] || m.Delta < 0 {
+				reserved[m.OrderId] -= m.Delta
+				continue
+			}
+			// A return: the units come back at the cost they shipped at.
+			key, sale = "order/"+m.OrderId, !at.Before(cogsFrom)
+		case pb.StockMovementReason_TRANSFER_OUT, pb.StockMovementReason_TRANSFER_IN, pb.StockMovementReason_TRANSFER_REVERSED:
+			key = transferKey + m.TransferId
+		}
+
+		if m.Delta < 0 {
+			pieces := book.take(-m.Delta)
+			if key != "" {
+				book.taken[key] = append(book.taken[key], pieces...)
+			}
+			if sale {
+				cogs.Add(cogs, sumPieces(pieces))
+			}
+			continue
+		}
+		if m.UnitCost != nil {
+			if m.UnitCost.CurrencyCode != currency {
+				return nil, failedPrecondition("CURRENCY", product.Id, fmt.Sprintf(
+					"product %q is priced in %s but was received at a cost in %s",
+					product.Id, currency, m.UnitCost.CurrencyCode))
+			}
+			total := moneyToNanos(m.UnitCost)
+			book.add(costPiece{quantity: m.Delta, total: total.Mul(total, big.NewInt(int64(m.Delta))), receiveTime: m.Time})
+			continue
+		}
+		var back []costPiece
+		if key != "" {
+			back = book.giveBack(key, m.Delta)
+		}
+		returned := sumPieces(back)
+		if sale {
+			cogs.Sub(cogs, returned)
+		}
+		rest := m.Delta
+		for _, p := range back {
+			rest -= p.quantity
+			p.receiveTime = m.Time
+			book.add(p)
+		}
+		book.add(book.atAverage(rest, m.Time))
+	}
+	v := &pb.ProductValuation{ProductId: product.Id, Quantity: book.quantity}
+	if v.Value, err = nanosToMoney(currency, book.value); err != nil {
+		return nil, err
+	}
+	if v.CostOfGoodsSold, err = nanosToMoney(currency, cogs); err != nil {
+		return nil, err
+	}
+	if book.quantity > 0 {
+		v.UnitCost, _ = nanosToMoney(currency, book.atAverage(1, nil).total)
+	}
+	inTransit := new(big.Int)
+	for key, pieces := range book.taken {
+		if strings.HasPrefix(key, transferKey) {
+			for _, p := range pieces {
+				v.InTransitQuantity += p.quantity
+			}
+			inTransit.Add(inTransit, sumPieces(pieces))
+		}
+	}
+	if v.InTransitValue, err = nanosToMoney(currency, inTransit); err != nil {
+		return nil, err
+	}
+	if method == pb.CostMethod_COST_METHOD_LIFO {
+		slices.Reverse(book.layers)
+	}
+	for _, layer := range book.layers {
+		unit := new(big.Int).Quo(layer.total, big.NewInt(int64(layer.quantity)))
+		cost, err := nanosToMoney(currency, unit)
+		if err != nil {
+			return nil, err
+		}
+		v.Layers = append(v.Layers, &pb.CostLayer{Quantity: layer.quantity, UnitCost: cost, ReceiveTime: layer.receiveTime})
+	}
+	return v, nil
+}
+
+// GetInventoryValuation values products' stock at cost from the ledger, as
+// it stood at as_of, together with the cost of goods sold in the period.
+// Products priced in different currencies are refused rather than summed.
+func (s *server) GetInventoryValuation(ctx context.Context, req *pb.GetInventoryValuationRequest) (*pb.InventoryValuation, error) {
+	if _, ok := pb.CostMethod_name[int32(req.Method)]; !ok || req.Method == pb.CostMethod_COST_METHOD_UNSPECIFIED {
+		return nil, invalidArgument("method", "must be FIFO, LIFO or WEIGHTED_AVERAGE")
+	}
+	asOf := time.Now()
+	if req.AsOf != nil {
+		if err := req.AsOf.CheckValid(); err != nil {
+			return nil, invalidArgument("as_of", err.Error())
+		}
+		asOf = req.AsOf.AsTime()
+	}
+	var cogsFrom time.Time
+	if req.CogsFrom != nil {
+		if err := req.CogsFrom.CheckValid(); err != nil {
+			return nil, invalidArgument("cogs_from", err.Error())
+		}
+		cogsFrom = req.CogsFrom.AsTime()
+		if cogsFrom.After(asOf) {
+			return nil, invalidArgument("cogs_from", "must not be after as_of")
+		}
+	}
+	ids := slices.Clone(req.ProductIds)
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+
+	res := &pb.InventoryValuation{Method: req.Method, AsOf: timestamppb.New(asOf)}
+	err := s.store.View(ctx, func(tx store.Tx) error {
+		var products []*pb.Product
+		if len(ids) == 0 {
+			var err error
+			if products, err = tx.Products().List(); err != nil {
+				return err
+			}
+		}
+		for _, id := range ids {
+			product, err := s.getProduct(tx, id)
+			if err != nil {
+				return err
+			}
+			products = append(products, product)
+		}
+		for _, product := range products {
+			normalizeProductPrice(product, s.currency)
+			v, err := s.valueProduct(tx, product, req.Method, asOf, cogsFrom)
+			if err != nil {
+				return err
+			}
+			res.Products = append(res.Products, v)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, storeError(err)
+	}
+
+	value, cogs := &pb.Money{CurrencyCode: s.currency}, &pb.Money{CurrencyCode: s.currency}
+	if len(res.Products) > 0 {
+		value.CurrencyCode = res.Products[0].Value.CurrencyCode
+		cogs.CurrencyCode = value.CurrencyCode
+	}
+	for _, v := range res.Products {
+		if v.Value.CurrencyCode != value.CurrencyCode {
+			return nil, failedPrecondition("CURRENCY", v.ProductId, fmt.Sprintf(
+				"product %q is valued in %s and product %q in %s; value products of one currency at a time",
+				res.Products[0].ProductId, value.CurrencyCode, v.ProductId, v.Value.CurrencyCode))
+		}
+		if value, err = addMoney(value, v.Value); err != nil {
+			return nil, err
+		}
+		if cogs, err = addMoney(cogs, v.CostOfGoodsSold); err != nil {
+			return nil, err
+		}
+	}
+	res.TotalValue, res.TotalCostOfGoodsSold = value, cogs
+	return res, nil
+}
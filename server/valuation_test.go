@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "github.com/kingztech2019/proto_grpc/proto_inventory/grpc-inventory-proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// mark returns a time strictly between the movements made before and after
+// it
+func mark() *timestamppb.Timestamp {
+	time.Sleep(time.Millisecond)
+	defer time.Sleep(time.Millisecond)
+	return timestamppb.Now()
+}
+
+// valuation values product a
+func valuation(t *testing.T, s *server, method pb.CostMethod, asOf, cogsFrom *timestamppb.Timestamp) *pb.ProductValuation {
+	t.Helper()
+	res, err := s.GetInventoryValuation(context.Background(), &pb.GetInventoryValuationRequest{
+		Method: method, ProductIds: []string{"a"}, AsOf: asOf, CogsFrom: cogsFrom,
+	})
+	if err != nil {
+		t.Fatalf("GetInventoryValuation: %v", err)
+	}
+	return res.Products[0]
+}
+
+func TestInventoryValuation(t *testing.T) {
+	// Four units received at $1 and four at $3, then an order for five that
+	// ships, an order placed before cogs_from and cancelled after it, and
+	// finally the shipped order coming back.
+	s := newTestServer(t, nil)
+	createProduct(t, s, "a", 0)
+	receive(t, s, "a", 4, 1)
+	receive(t, s, "a", 4, 3)
+	received := mark()
+	shipped := createOrder(t, s, 0, line("a", 5))
+	reserved := mark()
+	advance(t, shipped.Id, s.ConfirmOrder, s.StartPicking, s.ShipOrder)
+	afterShipping := mark()
+	cancelled := createOrder(t, s, 0, line("a", 1))
+	periodStart := mark()
+	advance(t, cancelled.Id, s.CancelOrder)
+	periodEnd := mark()
+	advance(t, shipped.Id, s.DeliverOrder, s.ReturnOrder)
+
+	type want struct {
+		quantity    int32
+		value, cogs int64
+	}
+	tests := []struct {
+		name            string
+		asOf, cogsFrom  *timestamppb.Timestamp
+		fifo, lifo, avg want
+	}{{
+		name: "received",
+		asOf: received,
+		fifo: want{8, 16, 0}, lifo: want{8, 16, 0}, avg: want{8, 16, 0},
+	}, {
+		name: "reserved units are on hand",
+		asOf: reserved,
+		fifo: want{8, 16, 0}, lifo: want{8, 16, 0}, avg: want{8, 16, 0},
+	}, {
+		name: "shipped",
+		asOf: afterShipping,
+		fifo: want{3, 9, 7}, lifo: want{3, 3, 13}, avg: want{3, 6, 10},
+	}, {
+		name:     "shipment within the period",
+		asOf:     afterShipping,
+		cogsFrom: reserved,
+		fifo:     want{3, 9, 7}, lifo: want{3, 3, 13}, avg: want{3, 6, 10},
+	}, {
+		name:     "shipment before the period",
+		asOf:     afterShipping,
+		cogsFrom: afterShipping,
+		fifo:     want{3, 9, 0}, lifo: want{3, 3, 0}, avg: want{3, 6, 0},
+	}, {
+		name:     "release within the period of a reservation made before it",
+		asOf:     periodEnd,
+		cogsFrom: periodStart,
+		fifo:     want{3, 9, 0}, lifo: want{3, 3, 0}, avg: want{3, 6, 0},
+	}, {
+		name: "returned",
+		fifo: want{8, 16, 0}, lifo: want{8, 16, 0}, avg: want{8, 16, 0},
+	}, {
+		name:     "returned within the period",
+		cogsFrom: periodEnd,
+		fifo:     want{8, 16, -7}, lifo: want{8, 16, -13}, avg: want{8, 16, -10},
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for method, w := range map[pb.CostMethod]want{
+				pb.CostMethod_COST_METHOD_FIFO:             tt.fifo,
+				pb.CostMethod_COST_METHOD_LIFO:             tt.lifo,
+				pb.CostMethod_COST_METHOD_WEIGHTED_AVERAGE: tt.avg,
+			} {
+				v := valuation(t, s, method, tt.asOf, tt.cogsFrom)
+				got := want{v.Quantity, v.Value.Units, v.CostOfGoodsSold.Units}
+				if got != w {
+					t.Errorf("%s: quantity, value, cogs = %v, want %v", method, got, w)
+				}
+			}
+		})
+	}
+}
+
+func TestInventoryValuationLayers(t *testing.T) {
+	s := newTestServer(t, nil)
+	createProduct(t, s, "a", 0)
+	receive(t, s, "a", 4, 1)
+	receive(t, s, "a", 4, 3)
+	order := createOrder(t, s, 0, line("a", 5))
+	advance(t, order.Id, s.ConfirmOrder, s.StartPicking, s.ShipOrder)
+
+	tests := []struct {
+		method pb.CostMethod
+		want   [][2]int64 // quantity and unit cost, in the order they will be used
+	}{
+		{pb.CostMethod_COST_METHOD_FIFO, [][2]int64{{3, 3}}},
+		{pb.CostMethod_COST_METHOD_LIFO, [][2]int64{{3, 1}}},
+		{pb.CostMethod_COST_METHOD_WEIGHTED_AVERAGE, nil},
+	}
+	for _, tt := range tests {
+		v := valuation(t, s, tt.method, nil, nil)
+		var got [][2]int64
+		for _, layer := range v.Layers {
+			got = append(got, [2]int64{int64(layer.Quantity), layer.UnitCost.Units})
+		}
+		if len(got) != len(tt.want) || len(got) > 0 && got[0] != tt.want[0] {
+			t.Errorf("%s: layers = %v, want %v", tt.method, got, tt.want)
+		}
+	}
+}
+
+func TestInventoryValuationFollowsTheLedger(t *testing.T) {
+	// Units reserved through a converted hold are sold when the order
+	// ships, and a shipped order deleted afterwards stays sold.
+	s := newTestServer(t, nil)
+	ctx := context.Background()
+	createProduct(t, s, "a", 0)
+	receive(t, s, "a", 4, 2)
+	hold := createHold(t, s, "a", 3, time.Hour)
+	res, err := s.ConvertHoldToOrder(ctx, &pb.ConvertHoldToOrderRequest{HoldId: hold.Id})
+	if err != nil {
+		t.Fatalf("ConvertHoldToOrder: %v", err)
+	}
+	converted := mark()
+	advance(t, res.Order.Id, s.ConfirmOrder, s.StartPicking, s.ShipOrder)
+	if _, err := s.DeleteOrder(ctx, &pb.DeleteOrderRequest{OrderId: res.Order.Id}); err != nil {
+		t.Fatalf("DeleteOrder: %v", err)
+	}
+
+	if v := valuation(t, s, pb.CostMethod_COST_METHOD_FIFO, converted, nil); v.Quantity != 4 || v.CostOfGoodsSold.Units != 0 {
+		t.Errorf("before shipping: quantity %d, cogs %d, want 4, 0", v.Quantity, v.CostOfGoodsSold.Units)
+	}
+	if v := valuation(t, s, pb.CostMethod_COST_METHOD_FIFO, nil, nil); v.Quantity != 1 || v.CostOfGoodsSold.Units != 6 {
+		t.Errorf("after shipping: quantity %d, cogs %d, want 1, 6", v.Quantity, v.CostOfGoodsSold.Units)
+	}
+}
+
+func TestInventoryValuationRefusesMixedCurrencies(t *testing.T) {
+	s := newTestServer(t, nil)
+	ctx := context.Background()
+	createProduct(t, s, "a", 1)
+	_, err := s.CreateProduct(ctx, &pb.CreateProductRequest{
+		Product: &pb.Product{Id: "b", PriceMoney: &pb.Money{CurrencyCode: "EUR", Units: 10}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.GetInventoryValuation(ctx, &pb.GetInventoryValuationRequest{Method: pb.CostMethod_COST_METHOD_FIFO})
+	wantCode(t, err, codes.FailedPrecondition)
+
+	res, err := s.GetInventoryValuation(ctx, &pb.GetInventoryValuationRequest{
+		Method: pb.CostMethod_COST_METHOD_FIFO, ProductIds: []string{"b"},
+	})
+	if err != nil {
+		t.Fatalf("GetInventoryValuation of one currency: %v", err)
+	}
+	if res.TotalValue.GetCurrencyCode() != "EUR" {
+		t.Errorf("total value = %v, want EUR", res.TotalValue)
+	}
+}
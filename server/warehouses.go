@@ -0,0 +1,273 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	pb "github.com/kingztech2019/proto_grpc/proto_inventory/grpc-inventory-proto"
+	"github.com/kingztech2019/proto_grpc/proto_inventory/store"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+const defaultWarehouse = "default"
+
+// ensureDefaultWarehouse creates the default warehouse if the store does not
+// have it yet. Stock not held at any other warehouse is counted there.
+func (s *server) ensureDefaultWarehouse(ctx context.Context) error {
+	return s.store.Update(ctx, func(tx store.Tx) error {
+		_, err := tx.Warehouses().Get(s.defaultWarehouse)
+		if !errors.Is(err, store.ErrNotFound) {
+			return err
+		}
+		return tx.Warehouses().Create(&pb.Warehouse{Id: s.defaultWarehouse, Name: "Default"})
+	})
+}
+
+// getWarehouse loads a warehouse, reporting a missing one as NotFound
+func getWarehouse(tx store.Tx, id string) (*pb.Warehouse, error) {
+	warehouse, err := tx.Warehouses().Get(id)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, notFound(warehouseResource, id)
+	}
+	return warehouse, err
+}
+
+// stockLevels returns the per-warehouse breakdown of a product's
+// inventory_level, default warehouse first. Only the other warehouses are
+// stored; the default one holds the remainder.
+func (s *server) stockLevels(tx store.Tx, product *pb.Product) ([]*pb.StockLevel, error) {
+	stored, err := tx.Stock().ListByProduct(product.Id)
+	if err != nil {
+		return nil, err
+	}
+	def := &pb.StockLevel{ProductId: product.Id, WarehouseId: s.defaultWarehouse, Quantity: product.InventoryLevel}
+	levels := []*pb.StockLevel{def}
+	for _, level := range stored {
+		if level.WarehouseId == s.defaultWarehouse {
+			continue
+		}
+		def.Quantity -= level.Quantity
+		levels = append(levels, level)
+	}
+	return levels, nil
+}
+
+// stockAt returns how many units of product the warehouse holds
+func (s *server) stockAt(tx store.Tx, product *pb.Product, warehouseID string) (int32, error) {
+	if warehouseID != s.defaultWarehouse {
+		level, err := tx.Stock().Get(product.Id, warehouseID)
+		if errors.Is(err, store.ErrNotFound) {
+			return 0, nil
+		}
+		return level.GetQuantity(), err
+	}
+	levels, err := s.stockLevels(tx, product)
+	if err != nil {
+		return 0, err
+	}
+	return levels[0].Quantity, nil
+}
+
+// fulfillmentWarehouse picks the warehouse an order without one reserves
+// from: the first that holds every line's quantity in unexpired stock,
+// trying the default warehouse first and the rest in ID order. When none
+// can, the default warehouse is returned and reserving reports the
+// shortfall.
+func (s *server) fulfillmentWarehouse(tx store.Tx, deltas map[string]int32) (string, error) {
+	warehouses, err := tx.Warehouses().List()
+	if err != nil {
+		return "", err
+	}
+	candidates := []string{s.defaultWarehouse}
+	for _, w := range warehouses {
+		if w.Id != s.defaultWarehouse {
+			candidates = append(candidates, w.Id)
+		}
+	}
+
+	products := make(map[string]*pb.Product, len(deltas))
+	for productID := range deltas {
+		product, err := tx.Products().Get(productID)
+		if errors.Is(err, store.ErrNotFound) {
+			return s.defaultWarehouse, nil
+		}
+		if err != nil {
+			return "", err
+		}
+		products[productID] = product
+	}
+candidates:
+	for _, warehouseID := range candidates {
+		for productID, quantity := range deltas {
+			available, err := s.allocatable(tx, products[productID], warehouseID)
+			if err != nil {
+				return "", err
+			}
+			if available < quantity {
+				continue candidates
+			}
+		}
+		return warehouseID, nil
+	}
+	return s.defaultWarehouse, nil
+}
+
+// CreateWarehouse adds a warehouse, generating its ID when none is supplied
+func (s *server) CreateWarehouse(ctx context.Context, req *pb.CreateWarehouseRequest) (*pb.Warehouse, error) {
+	if req.Warehouse == nil {
+		return nil, invalidArgument("warehouse", "is required")
+	}
+	if req.Warehouse.Name == "" {
+		return nil, invalidArgument("warehouse.name", "is required")
+	}
+	warehouse := proto.Clone(req.Warehouse).(*pb.Warehouse)
+	if warehouse.Id == "" {
+		id, err := newID()
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to generate warehouse id: %v", err)
+		}
+		warehouse.Id = id
+	}
+	err := s.update(ctx, func(tx store.Tx) error {
+		err := tx.Warehouses().Create(warehouse)
+		if errors.Is(err, store.ErrAlreadyExists) {
+			return alreadyExists(warehouseResource, warehouse.Id)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, storeError(err)
+	}
+	return warehouse, nil
+}
+
+// GetWarehouse fetches a warehouse by its ID
+func (s *server) GetWarehouse(ctx context.Context, req *pb.GetWarehouseRequest) (*pb.Warehouse, error) {
+	var warehouse *pb.Warehouse
+	err := s.store.View(ctx, func(tx store.Tx) error {
+		var err error
+		warehouse, err = getWarehouse(tx, req.WarehouseId)
+		return err
+	})
+	if err != nil {
+		return nil, storeError(err)
+	}
+	return warehouse, nil
+}
+
+// ListWarehouses returns every warehouse ordered by ID
+func (s *server) ListWarehouses(ctx context.Context, req *pb.ListWarehousesRequest) (*pb.ListWarehousesResponse, error) {
+	res := &pb.ListWarehousesResponse{}
+	err := s.store.View(ctx, func(tx store.Tx) error {
+		var err error
+		res.Warehouses, err = tx.Warehouses().List()
+		return err
+	})
+	if err != nil {
+		return nil, storeError(err)
+	}
+	return res, nil
+}
+
+// DeleteWarehouse deletes an empty warehouse with no open counts, active
+// holds or transfers in transit. The default warehouse cannot be deleted.
+// Orders reserved from a deleted warehouse return their stock to the
+// default one.
+func (s *server) DeleteWarehouse(ctx context.Context, req *pb.DeleteWarehouseRequest) (*pb.DeleteWarehouseResponse, error) {
+	if req.WarehouseId == s.defaultWarehouse {
+		return nil, failedPrecondition("WAREHOUSE", req.WarehouseId, "the default warehouse cannot be deleted")
+	}
+	err := s.update(ctx, func(tx store.Tx) error {
+		if _, err := getWarehouse(tx, req.WarehouseId); err != nil {
+			return err
+		}
+		open, err := tx.Counts().ListOpen(req.WarehouseId)
+		if err != nil {
+			return err
+		}
+		if len(open) > 0 {
+			return failedPrecondition("COUNT_OPEN", req.WarehouseId,
+				fmt.Sprintf("warehouse %q is being counted by count %q", req.WarehouseId, open[0].Id))
+		}
+		hold, err := activeHoldOn(tx, "", req.WarehouseId)
+		if err != nil {
+			return err
+		}
+		if hold != nil {
+			return failedPrecondition("REFERENCED", req.WarehouseId,
+				fmt.Sprintf("warehouse %q holds stock for hold %q", req.WarehouseId, hold.Id))
+		}
+		transfer, err := transferThrough(tx, "", req.WarehouseId)
+		if err != nil {
+			return err
+		}
+		if transfer != nil {
+			return failedPrecondition("REFERENCED", req.WarehouseId,
+				fmt.Sprintf("warehouse %q has transfer %q in transit", req.WarehouseId, transfer.Id))
+		}
+		levels, err := tx.Stock().ListByWarehouse(req.WarehouseId)
+		if err != nil {
+			return err
+		}
+		for _, level := range levels {
+			if level.Quantity > 0 {
+				return failedPrecondition("STOCK", req.WarehouseId, fmt.Sprintf(
+					"warehouse %q still holds %d units of product %q", req.WarehouseId, level.Quantity, level.ProductId))
+			}
+			if err := tx.Stock().Delete(level.ProductId, level.WarehouseId); err != nil {
+				return err
+			}
+		}
+		return tx.Warehouses().Delete(req.WarehouseId)
+	})
+	if err != nil {
+		return nil, storeError(err)
+	}
+	return &pb.DeleteWarehouseResponse{}, nil
+}
+
+// SetStockLevel sets how many units of a product a warehouse holds. The
+// product's inventory_level changes by the same amount and the difference
+// is recorded as a count correction.
+func (s *server) SetStockLevel(ctx context.Context, req *pb.SetStockLevelRequest) (*pb.StockLevel, error) {
+	switch {
+	case req.ProductId == "":
+		return nil, invalidArgument("product_id", "is required")
+	case req.WarehouseId == "":
+		return nil, invalidArgument("warehouse_id", "is required")
+	case req.Quantity < 0:
+		return nil, invalidArgument("quantity", "must not be negative")
+	}
+	level := &pb.StockLevel{ProductId: req.ProductId, WarehouseId: req.WarehouseId, Quantity: req.Quantity}
+	err := s.update(ctx, func(tx store.Tx) error {
+		product, err := s.getProduct(tx, req.ProductId)
+		if err != nil {
+			return err
+		}
+		if _, err := getWarehouse(tx, req.WarehouseId); err != nil {
+			return err
+		}
+		current, err := s.stockAt(tx, product, req.WarehouseId)
+		if err != nil || current == req.Quantity {
+			return err
+		}
+		if product.Serialized {
+			return failedPrecondition("SERIALIZED", product.Id, fmt.Sprintf(
+				"stock of serialized product %q changes with ReceiveSerials and AdjustStock", product.Id))
+		}
+		_, err = s.moveStock(tx, product, &pb.StockMovement{
+			WarehouseId: req.WarehouseId,
+			Delta:       req.Quantity - current,
+			Reason:      pb.StockMovementReason_COUNT_CORRECTION,
+			Cause:       "SetStockLevel",
+		})
+		return err
+	})
+	if err != nil {
+		return nil, storeError(err)
+	}
+	return level, nil
+}
@@ -0,0 +1,388 @@
+package store
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+// DefaultSnapshotEvery is the number of logged transactions after which a
+// File store compacts its log into a snapshot.
+const DefaultSnapshotEvery = 1000
+
+const (
+	walFile      = "wal.log"
+	snapshotFile = "snapshot"
+
+	recordHeaderSize = 8 // uint32 payload length + uint32 CRC-32C
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// FileOptions configures OpenFile.
+type FileOptions struct {
+	// SnapshotEvery is the number of transactions appended to the log before
+	// it is compacted into a snapshot. Zero means DefaultSnapshotEvery.
+	SnapshotEvery int
+}
+
+// File is a Store that keeps its data in memory and makes it durable in a
+// directory on disk. Every committed Update is appended to a write-ahead log
+// and fsynced before it becomes visible. The log is periodically compacted
+// into a snapshot, and opening the directory replays the snapshot followed
+// by the log.
+type File struct {
+	*Memory
+
+	dir           string
+	wal           *os.File
+	logged        int // transactions in wal since the last snapshot
+	snapshotEvery int
+}
+
+// OpenFile opens the store kept in dir, creating the directory if needed.
+// A torn record at the end of the log, left by a crash in the middle of a
+// write, is discarded; any other corruption is reported as an error.
+func OpenFile(dir string, opts FileOptions) (*File, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	f := &File{
+		Memory:        NewMemory(),
+		dir:           dir,
+		snapshotEvery: opts.SnapshotEvery,
+	}
+	if f.snapshotEvery <= 0 {
+		f.snapshotEvery = DefaultSnapshotEvery
+	}
+
+	if err := f.loadSnapshot(); err != nil {
+		return nil, err
+	}
+	wal, err := os.OpenFile(filepath.Join(dir, walFile), os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.replay(wal); err != nil {
+		wal.Close()
+		return nil, err
+	}
+	f.scanEventBounds()
+	f.wal = wal
+	f.Memory.onCommit = f.commit
+	return f, nil
+}
+
+// Close closes the log. The data is already durable.
+func (f *File) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.wal == nil {
+		return nil
+	}
+	err := f.wal.Close()
+	f.wal = nil
+	return err
+}
+
+// commit appends a transaction to the log. It runs with the memory lock
+// held, so log order matches commit order.
+func (f *File) commit(muts []mutation) error {
+	if f.wal == nil {
+		return errors.New("store: file store is closed")
+	}
+	payload, err := encodeMutations(muts)
+	if err != nil {
+		return err
+	}
+	start, err := f.wal.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	err = appendRecord(f.wal, payload)
+	if err == nil {
+		err = f.wal.Sync()
+	}
+	if err != nil {
+		// Cut off whatever part of the record made it to disk so later
+		// appends do not follow a corrupt record.
+		f.wal.Truncate(start)
+		f.wal.Seek(start, io.SeekStart)
+		return fmt.Errorf("store: append to log: %w", err)
+	}
+	f.logged++
+	if f.logged >= f.snapshotEvery {
+		// The transaction is already durable in the log; a failed
+		// compaction only means the log keeps growing until the next try.
+		if err := f.snapshot(); err == nil {
+			f.logged = 0
+		}
+	}
+	return nil
+}
+
+// snapshot writes every table to a new snapshot file, atomically replaces
+// the old one and then empties the log. Replaying a log over a snapshot
+// that already contains it yields the same state, so a crash between the
+// two steps is harmless.
+func (f *File) snapshot() error {
+	tmp := filepath.Join(f.dir, snapshotFile+".tmp")
+	out, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+
+	for _, t := range f.tables {
+		err := t.each(func(id string, v proto.Message) error {
+			payload, err := encodeMutations([]mutation{{table: t.tableName(), id: id, value: v}})
+			if err != nil {
+				return err
+			}
+			return appendRecord(out, payload)
+		})
+		if err != nil {
+			out.Close()
+			return err
+		}
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, filepath.Join(f.dir, snapshotFile)); err != nil {
+		return err
+	}
+	if err := syncDir(f.dir); err != nil {
+		return err
+	}
+
+	if err := f.wal.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := f.wal.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	return f.wal.Sync()
+}
+
+func (f *File) loadSnapshot() error {
+	snap, err := os.Open(filepath.Join(f.dir, snapshotFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer snap.Close()
+
+	// Snapshots are renamed into place only once complete, so unlike the
+	// log any damage is an error.
+	end, err := readRecords(snap, f.applyRecord)
+	if err != nil {
+		return fmt.Errorf("store: snapshot: %w", err)
+	}
+	if info, err := snap.Stat(); err == nil && info.Size() != end {
+		return fmt.Errorf("store: snapshot: truncated at offset %d", end)
+	}
+	return nil
+}
+
+// replay applies the log and cuts off a torn final record, leaving the file
+// positioned for appending.
+func (f *File) replay(wal *os.File) error {
+	end, err := readRecords(wal, func(payload []byte) error {
+		f.logged++
+		return f.applyRecord(payload)
+	})
+	if err != nil {
+		return fmt.Errorf("store: log: %w", err)
+	}
+	if err := wal.Truncate(end); err != nil {
+		return err
+	}
+	if _, err := wal.Seek(end, io.SeekStart); err != nil {
+		return err
+	}
+	return wal.Sync()
+}
+
+func (f *File) applyRecord(payload []byte) error {
+	return decodeMutations(payload, func(table, id string, data []byte) error {
+		t, ok := f.tables[table]
+		if !ok {
+			return fmt.Errorf("unknown table %q", table)
+		}
+		return t.apply(id, data)
+	})
+}
+
+// appendRecord writes one length-prefixed, checksummed record.
+func appendRecord(w io.Writer, payload []byte) error {
+	buf := make([]byte, recordHeaderSize, recordHeaderSize+len(payload))
+	binary.LittleEndian.PutUint32(buf[0:4], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(buf[4:8], crc32.Checksum(payload, crcTable))
+	buf = append(buf, payload...)
+	_, err := w.Write(buf)
+	return err
+}
+
+// readRecords calls fn for each record from the start of r and returns the
+// offset just past the last intact record. Reading stops without error at a
+// record that runs past the end of the file with no intact record after its
+// header, or that fails its checksum as the very last record: both are what
+// an interrupted append leaves behind.
+func readRecords(r io.ReadSeeker, fn func(payload []byte) error) (int64, error) {
+	size, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	var offset int64
+	header := make([]byte, recordHeaderSize)
+	for offset < size {
+		if size-offset < recordHeaderSize {
+			return offset, nil
+		}
+		if _, err := io.ReadFull(r, header); err != nil {
+			return offset, err
+		}
+		n := int64(binary.LittleEndian.Uint32(header[0:4]))
+		sum := binary.LittleEndian.Uint32(header[4:8])
+		next := offset + recordHeaderSize + n
+		if next > size {
+			rest := make([]byte, size-offset-recordHeaderSize)
+			if _, err := io.ReadFull(r, rest); err != nil {
+				return offset, err
+			}
+			if holdsRecord(rest) {
+				return offset, fmt.Errorf("corrupt record at offset %d", offset)
+			}
+			return offset, nil
+		}
+		payload := make([]byte, n)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			return offset, err
+		}
+		if crc32.Checksum(payload, crcTable) != sum {
+			if next == size {
+				return offset, nil
+			}
+			return offset, fmt.Errorf("corrupt record at offset %d", offset)
+		}
+		if err := fn(payload); err != nil {
+			return offset, fmt.Errorf("record at offset %d: %w", offset, err)
+		}
+		offset = next
+	}
+	return offset, nil
+}
+
+// holdsRecord reports whether a complete record with a matching checksum
+// starts anywhere in b. What follows the header of a torn final record is
+// part of its payload and, barring a checksum collision, holds none; a
+// record whose length was corrupted is followed by the records after it.
+func holdsRecord(b []byte) bool {
+	for p := 0; p+recordHeaderSize <= len(b); p++ {
+		n := int(binary.LittleEndian.Uint32(b[p : p+4]))
+		end := p + recordHeaderSize + n
+		if n == 0 || end > len(b) {
+			continue
+		}
+		if crc32.Checksum(b[p+recordHeaderSize:end], crcTable) == binary.LittleEndian.Uint32(b[p+4:p+8]) {
+			return true
+		}
+	}
+	return false
+}
+
+// Record payloads are a sequence of protobuf-wire fields, three per write:
+// field 1 the table name, field 2 the row ID and field 3 the marshaled
+// message, or field 4 (empty) in its place for a delete.
+const (
+	fieldTable  protowire.Number = 1
+	fieldID     protowire.Number = 2
+	fieldValue  protowire.Number = 3
+	fieldDelete protowire.Number = 4
+)
+
+func encodeMutations(muts []mutation) ([]byte, error) {
+	var b []byte
+	opts := proto.MarshalOptions{Deterministic: true}
+	for _, m := range muts {
+		b = protowire.AppendTag(b, fieldTable, protowire.BytesType)
+		b = protowire.AppendString(b, m.table)
+		b = protowire.AppendTag(b, fieldID, protowire.BytesType)
+		b = protowire.AppendString(b, m.id)
+		if m.value == nil {
+			b = protowire.AppendTag(b, fieldDelete, protowire.BytesType)
+			b = protowire.AppendBytes(b, nil)
+			continue
+		}
+		data, err := opts.Marshal(m.value)
+		if err != nil {
+			return nil, err
+		}
+		b = protowire.AppendTag(b, fieldValue, protowire.BytesType)
+		b = protowire.AppendBytes(b, data)
+	}
+	return b, nil
+}
+
+func decodeMutations(b []byte, fn func(table, id string, data []byte) error) error {
+	var table, id string
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 || typ != protowire.BytesType {
+			return errors.New("malformed record")
+		}
+		b = b[n:]
+		v, n := protowire.ConsumeBytes(b)
+		if n < 0 {
+			return errors.New("malformed record")
+		}
+		b = b[n:]
+
+		switch num {
+		case fieldTable:
+			table = string(v)
+		case fieldID:
+			id = string(v)
+		case fieldValue, fieldDelete:
+			var data []byte
+			if num == fieldValue {
+				data = append([]byte{}, v...)
+			}
+			if err := fn(table, id, data); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("malformed record: unexpected field %d", num)
+		}
+	}
+	return nil
+}
+
+// syncDir fsyncs a directory so a rename inside it is durable.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
@@ -0,0 +1,195 @@
+package store_test
+
+import (
+	"context"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	pb "github.com/kingztech2019/proto_grpc/proto_inventory/grpc-inventory-proto"
+	"github.com/kingztech2019/proto_grpc/proto_inventory/store"
+	"github.com/kingztech2019/proto_grpc/proto_inventory/store/storetest"
+)
+
+func TestFile(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.Store {
+		// A small snapshot interval makes the suite cross compactions.
+		f, err := store.OpenFile(t.TempDir(), store.FileOptions{SnapshotEvery: 3})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return f
+	})
+}
+
+// openFile opens the store in dir, closing it when the test ends
+func openFile(t *testing.T, dir string, snapshotEvery int) *store.File {
+	t.Helper()
+	f, err := store.OpenFile(dir, store.FileOptions{SnapshotEvery: snapshotEvery})
+	if err != nil {
+		t.Fatalf("OpenFile: %v", err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+// createProducts commits one transaction per product ID
+func createProducts(t *testing.T, s store.Store, ids ...string) {
+	t.Helper()
+	for _, id := range ids {
+		err := s.Update(context.Background(), func(tx store.Tx) error {
+			return tx.Products().Create(&pb.Product{Id: id})
+		})
+		if err != nil {
+			t.Fatalf("Create(%q): %v", id, err)
+		}
+	}
+}
+
+// productIDs lists the IDs of the stored products
+func productIDs(t *testing.T, s store.Store) []string {
+	t.Helper()
+	var ids []string
+	err := s.View(context.Background(), func(tx store.Tx) error {
+		products, err := tx.Products().List()
+		for _, p := range products {
+			ids = append(ids, p.Id)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	return ids
+}
+
+func wantIDs(t *testing.T, got []string, want ...string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("products = %q, want %q", got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("products = %q, want %q", got, want)
+		}
+	}
+}
+
+// walRecords returns the offset of each record in the log and its size
+func walRecords(t *testing.T, path string) ([]int64, int64) {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var offsets []int64
+	for off := int64(0); off < int64(len(b)); off += 8 + int64(binary.LittleEndian.Uint32(b[off:])) {
+		offsets = append(offsets, off)
+	}
+	return offsets, int64(len(b))
+}
+
+func TestFileReplay(t *testing.T) {
+	dir := t.TempDir()
+	f := openFile(t, dir, 4)
+	createProducts(t, f, "a", "b", "c", "d", "e", "f")
+	err := f.Update(context.Background(), func(tx store.Tx) error { return tx.Products().Delete("c") })
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	// Four transactions went into the snapshot and three are in the log.
+	if offsets, _ := walRecords(t, filepath.Join(dir, "wal.log")); len(offsets) != 3 {
+		t.Errorf("log holds %d records, want 3", len(offsets))
+	}
+	wantIDs(t, productIDs(t, openFile(t, dir, 4)), "a", "b", "d", "e", "f")
+}
+
+func TestFileTornRecord(t *testing.T) {
+	dir := t.TempDir()
+	wal := filepath.Join(dir, "wal.log")
+	f := openFile(t, dir, 100)
+	createProducts(t, f, "a", "b", "c")
+	f.Close()
+
+	offsets, size := walRecords(t, wal)
+	last := offsets[len(offsets)-1]
+	for _, cut := range []int64{last + 3, last + 8, size - 1} {
+		if err := os.Truncate(wal, cut); err != nil {
+			t.Fatal(err)
+		}
+		f := openFile(t, dir, 100)
+		wantIDs(t, productIDs(t, f), "a", "b")
+		if _, got := walRecords(t, wal); got != last {
+			t.Errorf("cut at %d: log is %d bytes after recovery, want %d", cut, got, last)
+		}
+		// The log accepts appends after the tail is dropped.
+		createProducts(t, f, "c")
+		f.Close()
+		wantIDs(t, productIDs(t, openFile(t, dir, 100)), "a", "b", "c")
+		_, size = walRecords(t, wal)
+	}
+}
+
+func TestFileCorruptChecksum(t *testing.T) {
+	dir := t.TempDir()
+	wal := filepath.Join(dir, "wal.log")
+	f := openFile(t, dir, 100)
+	createProducts(t, f, "a", "b", "c")
+	f.Close()
+	offsets, size := walRecords(t, wal)
+
+	flip := func(off int64) {
+		t.Helper()
+		b, err := os.ReadFile(wal)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b[off] ^= 0xff
+		if err := os.WriteFile(wal, b, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A bad checksum on the final record is an interrupted append.
+	flip(size - 1)
+	wantIDs(t, productIDs(t, openFile(t, dir, 100)), "a", "b")
+
+	// Anywhere else it is corruption, and the store refuses to open.
+	flip(offsets[0] + 8)
+	if _, err := store.OpenFile(dir, store.FileOptions{}); err == nil {
+		t.Fatal("OpenFile succeeded over a corrupt record")
+	}
+}
+
+func TestFileCorruptLength(t *testing.T) {
+	dir := t.TempDir()
+	wal := filepath.Join(dir, "wal.log")
+	f := openFile(t, dir, 100)
+	createProducts(t, f, "a", "b", "c")
+	f.Close()
+	_, size := walRecords(t, wal)
+
+	// A first record whose length runs past the end of the file is not a
+	// torn append when committed records follow it.
+	b, err := os.ReadFile(wal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	binary.LittleEndian.PutUint32(b[0:4], uint32(size))
+	if err := os.WriteFile(wal, b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.OpenFile(dir, store.FileOptions{}); err == nil {
+		t.Fatal("OpenFile succeeded over a corrupt record length")
+	}
+	info, err := os.Stat(wal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() != size {
+		t.Errorf("log is %d bytes after a failed open, want %d", info.Size(), size)
+	}
+}
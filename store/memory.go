@@ -0,0 +1,863 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	pb "github.com/kingztech2019/proto_grpc/proto_inventory/grpc-inventory-proto"
+	"google.golang.org/protobuf/proto"
+)
+
+// Memory is a Store that keeps everything in process memory. Data is lost
+// when the process exits.
+type Memory struct {
+	mu         sync.RWMutex
+	products   *table[*pb.Product]
+	orders     *table[*pb.Order]
+	events     *table[*pb.InventoryEvent]
+	keys       *table[*pb.IdempotencyRecord]
+	warehouses *table[*pb.Warehouse]
+	stock      *table[*pb.StockLevel]
+	movements  *table[*pb.StockMovement]
+	alerts     *table[*pb.Alert]
+	transfers  *table[*pb.Transfer]
+	lots       *table[*pb.Lot]
+	serials    *table[*pb.SerialNumber]
+	counts     *table[*pb.CycleCount]
+	holds      *table[*pb.Hold]
+	tables     map[string]anyTable // every table above, by name
+
+	// eventBounds holds the first and last event sequence, or zeros when
+	// there are no events. It is only written under the exclusive lock, so
+	// readers under the shared lock see a consistent value.
+	eventBounds [2]uint64
+
+	// onCommit, when set, receives the writes of each successful Update
+	// while the lock is still held. If it fails the writes are undone and
+	// the Update returns its error.
+	onCommit func([]mutation) error
+}
+
+// NewMemory returns an empty in-memory store.
+func NewMemory() *Memory {
+	m := &Memory{
+		products:   newTable[*pb.Product]("products"),
+		orders:     newTable[*pb.Order]("orders"),
+		events:     newTable[*pb.InventoryEvent]("inventory_events"),
+		keys:       newTable[*pb.IdempotencyRecord]("idempotency_keys"),
+		warehouses: newTable[*pb.Warehouse]("warehouses"),
+		stock:      newTable[*pb.StockLevel]("stock_levels"),
+		movements:  newTable[*pb.StockMovement]("stock_movements"),
+		alerts:     newTable[*pb.Alert]("alerts"),
+		transfers:  newTable[*pb.Transfer]("transfers"),
+		lots:       newTable[*pb.Lot]("lots"),
+		serials:    newTable[*pb.SerialNumber]("serial_numbers"),
+		counts:     newTable[*pb.CycleCount]("cycle_counts"),
+		holds:      newTable[*pb.Hold]("holds"),
+	}
+	m.orders.addIndex("product", func(o *pb.Order) []string {
+		var ids []string
+		for _, line := range orderLines(o) {
+			ids = append(ids, line.ProductId)
+		}
+		return ids
+	})
+	m.orders.addIndex("status", func(o *pb.Order) []string { return []string{o.Status.String()} })
+	m.orders.addSorted("date", func(a, b *pb.Order) int {
+		return compareOrders(a.OrderDate.AsTime(), a.Id, b.OrderDate.AsTime(), b.Id)
+	})
+	m.stock.addIndex("product", func(l *pb.StockLevel) []string { return []string{l.ProductId} })
+	m.stock.addIndex("warehouse", func(l *pb.StockLevel) []string { return []string{l.WarehouseId} })
+	m.lots.addIndex("product", func(l *pb.Lot) []string { return []string{l.ProductId} })
+	m.counts.addIndex("open", func(c *pb.CycleCount) []string {
+		if c.Status != pb.CountStatus_COUNT_STATUS_OPEN {
+			return nil
+		}
+		return []string{c.WarehouseId}
+	})
+	m.holds.addIndex("active", func(h *pb.Hold) []string {
+		if h.Status != pb.HoldStatus_HOLD_STATUS_ACTIVE {
+			return nil
+		}
+		return []string{""}
+	})
+	m.transfers.addIndex("in_transit", func(t *pb.Transfer) []string {
+		if t.Status != pb.TransferStatus_TRANSFER_STATUS_IN_TRANSIT {
+			return nil
+		}
+		return []string{""}
+	})
+	m.movements.addIndex("product", func(mv *pb.StockMovement) []string { return []string{mv.ProductId} })
+	m.tables = make(map[string]anyTable)
+	for _, t := range []anyTable{m.products, m.orders, m.events, m.keys, m.warehouses, m.stock, m.movements, m.alerts, m.transfers, m.lots, m.serials, m.counts, m.holds} {
+		m.tables[t.tableName()] = t
+	}
+	return m
+}
+
+// View runs fn under a shared lock.
+func (m *Memory) View(ctx context.Context, fn func(Tx) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return fn(&memTx{m: m})
+}
+
+// Update runs fn under an exclusive lock, undoing its writes if it fails or
+// panics.
+func (m *Memory) Update(ctx context.Context, fn func(Tx) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tx := &memTx{m: m, writable: true}
+	committed := false
+	defer func() {
+		if !committed {
+			tx.rollback()
+		}
+	}()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if m.onCommit != nil && len(tx.log) > 0 {
+		if err := m.onCommit(tx.log); err != nil {
+			return err
+		}
+	}
+	committed = true
+	return nil
+}
+
+// Close is a no-op for the in-memory store.
+func (m *Memory) Close() error {
+	return nil
+}
+
+// table holds the messages of one record type keyed by ID. Stored messages
+// are never modified in place, only replaced.
+type table[T proto.Message] struct {
+	name    string
+	rows    map[string]T
+	indexes map[string]*index[T]
+	sorted  map[string]*sortedIndex[T]
+}
+
+// index maps the keys derived from each row to the IDs of the rows that
+// produce them, so lookups by key do not scan the table.
+type index[T proto.Message] struct {
+	keys func(T) []string
+	ids  map[string]map[string]struct{}
+}
+
+// sortedIndex keeps every row in the order cmp defines, so range scans seek
+// to their start instead of sorting the table. cmp must be a total order,
+// breaking ties by ID.
+type sortedIndex[T proto.Message] struct {
+	cmp  func(a, b T) int
+	rows []T
+}
+
+func newTable[T proto.Message](name string) *table[T] {
+	return &table[T]{
+		name:    name,
+		rows:    make(map[string]T),
+		indexes: make(map[string]*index[T]),
+		sorted:  make(map[string]*sortedIndex[T]),
+	}
+}
+
+// addIndex registers a secondary index. It must be called while the table
+// is still empty.
+func (t *table[T]) addIndex(name string, keys func(T) []string) {
+	t.indexes[name] = &index[T]{keys: keys, ids: make(map[string]map[string]struct{})}
+}
+
+// addSorted registers a sorted index. It must be called while the table is
+// still empty.
+func (t *table[T]) addSorted(name string, cmp func(a, b T) int) {
+	t.sorted[name] = &sortedIndex[T]{cmp: cmp}
+}
+
+// put stores v under id, keeping the indexes up to date.
+func (t *table[T]) put(id string, v T) {
+	t.del(id)
+	t.rows[id] = v
+	for _, idx := range t.sorted {
+		i, _ := slices.BinarySearchFunc(idx.rows, v, idx.cmp)
+		idx.rows = slices.Insert(idx.rows, i, v)
+	}
+	for _, idx := range t.indexes {
+		for _, key := range idx.keys(v) {
+			if idx.ids[key] == nil {
+				idx.ids[key] = make(map[string]struct{})
+			}
+			idx.ids[key][id] = struct{}{}
+		}
+	}
+}
+
+// del removes the row with id, if any, from the table and its indexes.
+func (t *table[T]) del(id string) {
+	old, ok := t.rows[id]
+	if !ok {
+		return
+	}
+	delete(t.rows, id)
+	for _, idx := range t.sorted {
+		if i, found := slices.BinarySearchFunc(idx.rows, old, idx.cmp); found {
+			idx.rows = slices.Delete(idx.rows, i, i+1)
+		}
+	}
+	for _, idx := range t.indexes {
+		for _, key := range idx.keys(old) {
+			delete(idx.ids[key], id)
+			if len(idx.ids[key]) == 0 {
+				delete(idx.ids, key)
+			}
+		}
+	}
+}
+
+// lookup returns the IDs of the rows whose index keys include key.
+func (t *table[T]) lookup(index, key string) []string {
+	ids := make([]string, 0, len(t.indexes[index].ids[key]))
+	for id := range t.indexes[index].ids[key] {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// seek returns the rows of a sorted index from the first one at or after
+// the position probe describes: probe reports whether a row sorts before
+// it (negative), at it (zero) or after it (positive). The slice is shared
+// with the index and must not be modified or kept past the transaction.
+func (t *table[T]) seek(index string, probe func(T) int) []T {
+	rows := t.sorted[index].rows
+	i := sort.Search(len(rows), func(i int) bool { return probe(rows[i]) >= 0 })
+	return rows[i:]
+}
+
+// rowsByID returns the stored rows with the given IDs, skipping
+// duplicates. The rows are shared with the table and must not be modified.
+func (t *table[T]) rowsByID(ids []string) []T {
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+	rows := make([]T, len(ids))
+	for i, id := range ids {
+		rows[i] = t.rows[id]
+	}
+	return rows
+}
+
+// anyTable is the type-erased view of a table used to persist and restore
+// its contents.
+type anyTable interface {
+	tableName() string
+	// apply sets the row to the unmarshaled data, or deletes it if data is nil.
+	apply(id string, data []byte) error
+	// each calls fn for every row.
+	each(fn func(id string, v proto.Message) error) error
+}
+
+func (t *table[T]) tableName() string { return t.name }
+
+func (t *table[T]) apply(id string, data []byte) error {
+	if data == nil {
+		t.del(id)
+		return nil
+	}
+	var zero T
+	v := zero.ProtoReflect().Type().New().Interface().(T)
+	if err := proto.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%s %q: %w", t.name, id, err)
+	}
+	t.put(id, v)
+	return nil
+}
+
+func (t *table[T]) each(fn func(id string, v proto.Message) error) error {
+	for id, v := range t.rows {
+		if err := fn(id, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mutation is one committed write. value is nil for deletes.
+type mutation struct {
+	table string
+	id    string
+	value proto.Message
+}
+
+// memTx records how to reverse every write so a failed Update leaves the
+// tables untouched, along with the writes themselves for onCommit.
+type memTx struct {
+	m        *Memory
+	writable bool
+	undo     []func()
+	log      []mutation
+}
+
+func (tx *memTx) Products() ProductRepository        { return memProducts{tx} }
+func (tx *memTx) Orders() OrderRepository            { return memOrders{tx} }
+func (tx *memTx) Events() EventRepository            { return memEvents{tx} }
+func (tx *memTx) Idempotency() IdempotencyRepository { return memIdempotency{tx} }
+func (tx *memTx) Warehouses() WarehouseRepository    { return memWarehouses{tx} }
+func (tx *memTx) Stock() StockRepository             { return memStock{tx} }
+func (tx *memTx) Movements() MovementRepository      { return memMovements{tx} }
+func (tx *memTx) Alerts() AlertRepository            { return memAlerts{tx} }
+func (tx *memTx) Transfers() TransferRepository      { return memTransfers{tx} }
+func (tx *memTx) Lots() LotRepository                { return memLots{tx} }
+func (tx *memTx) Serials() SerialRepository          { return memSerials{tx} }
+func (tx *memTx) Counts() CountRepository            { return memCounts{tx} }
+func (tx *memTx) Holds() HoldRepository              { return memHolds{tx} }
+
+func (tx *memTx) rollback() {
+	for i := len(tx.undo) - 1; i >= 0; i-- {
+		tx.undo[i]()
+	}
+	tx.undo = nil
+}
+
+func clone[T proto.Message](v T) T {
+	return proto.Clone(v).(T)
+}
+
+func get[T proto.Message](t *table[T], id string) (T, error) {
+	v, ok := t.rows[id]
+	if !ok {
+		var zero T
+		return zero, ErrNotFound
+	}
+	return clone(v), nil
+}
+
+// list returns copies of the rows accepted by keep, ordered by ID.
+func list[T proto.Message](t *table[T], keep func(T) bool) []T {
+	ids := make([]string, 0, len(t.rows))
+	for id, v := range t.rows {
+		if keep == nil || keep(v) {
+			ids = append(ids, id)
+		}
+	}
+	return listIDs(t, ids)
+}
+
+// listIDs returns copies of the rows with the given IDs, ordered by ID.
+func listIDs[T proto.Message](t *table[T], ids []string) []T {
+	slices.Sort(ids)
+	out := make([]T, len(ids))
+	for i, id := range ids {
+		out[i] = clone(t.rows[id])
+	}
+	return out
+}
+
+// set writes a copy of v under id. mustExist selects update semantics;
+// otherwise the ID must be unused.
+func set[T proto.Message](tx *memTx, t *table[T], id string, v T, mustExist bool) error {
+	if !tx.writable {
+		return ErrReadOnly
+	}
+	old, exists := t.rows[id]
+	switch {
+	case mustExist && !exists:
+		return ErrNotFound
+	case !mustExist && exists:
+		return ErrAlreadyExists
+	}
+	stored := clone(v)
+	t.put(id, stored)
+	tx.undo = append(tx.undo, func() {
+		if exists {
+			t.put(id, old)
+		} else {
+			t.del(id)
+		}
+	})
+	tx.log = append(tx.log, mutation{table: t.name, id: id, value: stored})
+	return nil
+}
+
+func remove[T proto.Message](tx *memTx, t *table[T], id string) error {
+	if !tx.writable {
+		return ErrReadOnly
+	}
+	old, exists := t.rows[id]
+	if !exists {
+		return ErrNotFound
+	}
+	t.del(id)
+	tx.undo = append(tx.undo, func() { t.put(id, old) })
+	tx.log = append(tx.log, mutation{table: t.name, id: id})
+	return nil
+}
+
+type memProducts struct{ tx *memTx }
+
+func (r memProducts) Get(id string) (*pb.Product, error) {
+	return get(r.tx.m.products, id)
+}
+
+func (r memProducts) Create(product *pb.Product) error {
+	return set(r.tx, r.tx.m.products, product.Id, product, false)
+}
+
+func (r memProducts) Update(product *pb.Product) error {
+	return set(r.tx, r.tx.m.products, product.Id, product, true)
+}
+
+func (r memProducts) Delete(id string) error {
+	return remove(r.tx, r.tx.m.products, id)
+}
+
+func (r memProducts) List() ([]*pb.Product, error) {
+	return list(r.tx.m.products, nil), nil
+}
+
+type memOrders struct{ tx *memTx }
+
+func (r memOrders) Get(id string) (*pb.Order, error) {
+	return get(r.tx.m.orders, id)
+}
+
+func (r memOrders) Create(order *pb.Order) error {
+	return set(r.tx, r.tx.m.orders, order.Id, order, false)
+}
+
+func (r memOrders) Update(order *pb.Order) error {
+	return set(r.tx, r.tx.m.orders, order.Id, order, true)
+}
+
+func (r memOrders) Delete(id string) error {
+	return remove(r.tx, r.tx.m.orders, id)
+}
+
+func (r memOrders) ListByProduct(productID string) ([]*pb.Order, error) {
+	return listIDs(r.tx.m.orders, r.tx.m.orders.lookup("product", productID)), nil
+}
+
+func (r memOrders) Query(q OrderQuery) ([]*pb.Order, error) {
+	t := r.tx.m.orders
+	var rows []*pb.Order
+	switch {
+	case q.ProductID != "":
+		rows = t.rowsByID(t.lookup("product", q.ProductID))
+	case len(q.Statuses) > 0:
+		var ids []string
+		for _, status := range q.Statuses {
+			ids = append(ids, t.lookup("status", status.String())...)
+		}
+		rows = t.rowsByID(ids)
+	default:
+		// Seek past the orders before From and up to the cursor rather than
+		// filtering them one by one.
+		rows = t.seek("date", func(o *pb.Order) int {
+			date := o.OrderDate.AsTime()
+			if !q.From.IsZero() && date.Before(q.From) ||
+				q.AfterID != "" && compareOrders(date, o.Id, q.AfterDate, q.AfterID) <= 0 {
+				return -1
+			}
+			return 1
+		})
+	}
+	if q.ProductID != "" || len(q.Statuses) > 0 {
+		slices.SortFunc(rows, func(a, b *pb.Order) int {
+			return compareOrders(a.OrderDate.AsTime(), a.Id, b.OrderDate.AsTime(), b.Id)
+		})
+	}
+
+	var matches []*pb.Order
+	for _, o := range rows {
+		if o.OrderDate == nil {
+			continue
+		}
+		date, quantity := o.OrderDate.AsTime(), orderQuantity(o)
+		if !q.To.IsZero() && !date.Before(q.To) {
+			break
+		}
+		switch {
+		case q.ProductID != "" && len(q.Statuses) > 0 && !slices.Contains(q.Statuses, o.Status),
+			!q.From.IsZero() && date.Before(q.From),
+			q.MinQuantity != 0 && quantity < q.MinQuantity,
+			q.MaxQuantity != 0 && quantity > q.MaxQuantity,
+			q.Backordered && !isBackordered(o, q.ProductID),
+			q.AfterID != "" && compareOrders(date, o.Id, q.AfterDate, q.AfterID) <= 0:
+			continue
+		}
+		matches = append(matches, clone(o))
+		if q.Limit > 0 && len(matches) == q.Limit {
+			break
+		}
+	}
+	return matches, nil
+}
+
+// compareOrders orders orders by date, then ID.
+func compareOrders(aDate time.Time, aID string, bDate time.Time, bID string) int {
+	if c := aDate.Compare(bDate); c != 0 {
+		return c
+	}
+	return strings.Compare(aID, bID)
+}
+
+type memEvents struct{ tx *memTx }
+
+// eventKey is the table key for a sequence, zero-padded so keys sort in
+// sequence order.
+func eventKey(seq uint64) string {
+	return fmt.Sprintf("%020d", seq)
+}
+
+// scanEventBounds recomputes eventBounds from the events table, for rows
+// loaded without going through Append.
+func (m *Memory) scanEventBounds() {
+	var b [2]uint64
+	for key := range m.events.rows {
+		seq, _ := strconv.ParseUint(key, 10, 64)
+		if b[0] == 0 || seq < b[0] {
+			b[0] = seq
+		}
+		b[1] = max(b[1], seq)
+	}
+	m.eventBounds = b
+}
+
+func (r memEvents) bounds() [2]uint64 {
+	return r.tx.m.eventBounds
+}
+
+// setBounds updates the bounds, restoring them on rollback.
+func (r memEvents) setBounds(b [2]uint64) {
+	m := r.tx.m
+	old := m.eventBounds
+	m.eventBounds = b
+	r.tx.undo = append(r.tx.undo, func() { m.eventBounds = old })
+}
+
+func (r memEvents) Append(event *pb.InventoryEvent) error {
+	if !r.tx.writable {
+		return ErrReadOnly
+	}
+	b := r.bounds()
+	event.Sequence = b[1] + 1
+	if err := set(r.tx, r.tx.m.events, eventKey(event.Sequence), event, false); err != nil {
+		return err
+	}
+	if b[0] == 0 {
+		b[0] = event.Sequence
+	}
+	b[1] = event.Sequence
+	r.setBounds(b)
+	return nil
+}
+
+func (r memEvents) ListAfter(after uint64, limit int) ([]*pb.InventoryEvent, error) {
+	events := list(r.tx.m.events, func(e *pb.InventoryEvent) bool { return e.Sequence > after })
+	if limit > 0 && len(events) > limit {
+		events = events[:limit]
+	}
+	return events, nil
+}
+
+func (r memEvents) Bounds() (first, last uint64, err error) {
+	b := r.bounds()
+	return b[0], b[1], nil
+}
+
+func (r memEvents) DeleteBefore(seq uint64) error {
+	if !r.tx.writable {
+		return ErrReadOnly
+	}
+	b := r.bounds()
+	seq = min(seq, b[1])
+	if b[0] >= seq {
+		return nil
+	}
+	for key, e := range r.tx.m.events.rows {
+		if e.Sequence < seq {
+			if err := remove(r.tx, r.tx.m.events, key); err != nil {
+				return err
+			}
+		}
+	}
+	b[0] = seq
+	r.setBounds(b)
+	return nil
+}
+
+type memIdempotency struct{ tx *memTx }
+
+func (r memIdempotency) Get(key string) (*pb.IdempotencyRecord, error) {
+	return get(r.tx.m.keys, key)
+}
+
+func (r memIdempotency) Create(record *pb.IdempotencyRecord) error {
+	return set(r.tx, r.tx.m.keys, record.Key, record, false)
+}
+
+func (r memIdempotency) Update(record *pb.IdempotencyRecord) error {
+	return set(r.tx, r.tx.m.keys, record.Key, record, true)
+}
+
+func (r memIdempotency) DeleteExpired(now time.Time) (int, error) {
+	if !r.tx.writable {
+		return 0, ErrReadOnly
+	}
+	n := 0
+	for key, record := range r.tx.m.keys.rows {
+		if record.ExpireTime.AsTime().Before(now) {
+			if err := remove(r.tx, r.tx.m.keys, key); err != nil {
+				return n, err
+			}
+			n++
+		}
+	}
+	return n, nil
+}
+
+type memWarehouses struct{ tx *memTx }
+
+func (r memWarehouses) Get(id string) (*pb.Warehouse, error) {
+	return get(r.tx.m.warehouses, id)
+}
+
+func (r memWarehouses) Create(warehouse *pb.Warehouse) error {
+	return set(r.tx, r.tx.m.warehouses, warehouse.Id, warehouse, false)
+}
+
+func (r memWarehouses) Delete(id string) error {
+	return remove(r.tx, r.tx.m.warehouses, id)
+}
+
+func (r memWarehouses) List() ([]*pb.Warehouse, error) {
+	return list(r.tx.m.warehouses, nil), nil
+}
+
+type memStock struct{ tx *memTx }
+
+func (r memStock) Get(productID, warehouseID string) (*pb.StockLevel, error) {
+	return get(r.tx.m.stock, stockKey(productID, warehouseID))
+}
+
+func (r memStock) Put(level *pb.StockLevel) error {
+	key := stockKey(level.ProductId, level.WarehouseId)
+	_, exists := r.tx.m.stock.rows[key]
+	return set(r.tx, r.tx.m.stock, key, level, exists)
+}
+
+func (r memStock) Delete(productID, warehouseID string) error {
+	return remove(r.tx, r.tx.m.stock, stockKey(productID, warehouseID))
+}
+
+func (r memStock) ListByProduct(productID string) ([]*pb.StockLevel, error) {
+	return listIDs(r.tx.m.stock, r.tx.m.stock.lookup("product", productID)), nil
+}
+
+func (r memStock) ListByWarehouse(warehouseID string) ([]*pb.StockLevel, error) {
+	levels := listIDs(r.tx.m.stock, r.tx.m.stock.lookup("warehouse", warehouseID))
+	slices.SortFunc(levels, func(a, b *pb.StockLevel) int { return strings.Compare(a.ProductId, b.ProductId) })
+	return levels, nil
+}
+
+type memMovements struct{ tx *memTx }
+
+// Append numbers movements from the row count, which works because the
+// ledger is never pruned.
+func (r memMovements) Append(movement *pb.StockMovement) error {
+	if !r.tx.writable {
+		return ErrReadOnly
+	}
+	movement.Sequence = uint64(len(r.tx.m.movements.rows)) + 1
+	return set(r.tx, r.tx.m.movements, eventKey(movement.Sequence), movement, false)
+}
+
+func (r memMovements) Query(q MovementQuery) ([]*pb.StockMovement, error) {
+	t := r.tx.m.movements
+	var ids []string
+	if q.ProductID != "" {
+		ids = t.lookup("product", q.ProductID)
+	} else {
+		ids = make([]string, 0, len(t.rows))
+		for id := range t.rows {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+
+	var matches []*pb.StockMovement
+	for _, id := range ids {
+		mv := t.rows[id]
+		switch {
+		case q.WarehouseID != "" && mv.WarehouseId != q.WarehouseID,
+			q.Reason != pb.StockMovementReason_STOCK_MOVEMENT_REASON_UNSPECIFIED && mv.Reason != q.Reason,
+			mv.Sequence <= q.AfterSequence:
+			continue
+		}
+		matches = append(matches, clone(mv))
+		if q.Limit > 0 && len(matches) == q.Limit {
+			break
+		}
+	}
+	return matches, nil
+}
+
+func (r memMovements) Totals(productID string) (map[string]int32, error) {
+	totals := make(map[string]int32)
+	for _, id := range r.tx.m.movements.lookup("product", productID) {
+		mv := r.tx.m.movements.rows[id]
+		totals[mv.WarehouseId] += mv.Delta
+	}
+	return totals, nil
+}
+
+type memAlerts struct{ tx *memTx }
+
+// Append numbers alerts from the row count, as alerts are never removed.
+func (r memAlerts) Append(alert *pb.Alert) error {
+	if !r.tx.writable {
+		return ErrReadOnly
+	}
+	alert.Sequence = uint64(len(r.tx.m.alerts.rows)) + 1
+	return set(r.tx, r.tx.m.alerts, eventKey(alert.Sequence), alert, false)
+}
+
+func (r memAlerts) ListAfter(after uint64, limit int) ([]*pb.Alert, error) {
+	alerts := list(r.tx.m.alerts, func(a *pb.Alert) bool { return a.Sequence > after })
+	if limit > 0 && len(alerts) > limit {
+		alerts = alerts[:limit]
+	}
+	return alerts, nil
+}
+
+func (r memAlerts) Last() (uint64, error) {
+	return uint64(len(r.tx.m.alerts.rows)), nil
+}
+
+type memTransfers struct{ tx *memTx }
+
+func (r memTransfers) Get(id string) (*pb.Transfer, error) {
+	return get(r.tx.m.transfers, id)
+}
+
+func (r memTransfers) Create(transfer *pb.Transfer) error {
+	return set(r.tx, r.tx.m.transfers, transfer.Id, transfer, false)
+}
+
+func (r memTransfers) Update(transfer *pb.Transfer) error {
+	return set(r.tx, r.tx.m.transfers, transfer.Id, transfer, true)
+}
+
+func (r memTransfers) ListInTransit() ([]*pb.Transfer, error) {
+	return listIDs(r.tx.m.transfers, r.tx.m.transfers.lookup("in_transit", "")), nil
+}
+
+type memLots struct{ tx *memTx }
+
+func (r memLots) Get(id string) (*pb.Lot, error) {
+	return get(r.tx.m.lots, id)
+}
+
+func (r memLots) Create(lot *pb.Lot) error {
+	return set(r.tx, r.tx.m.lots, lot.Id, lot, false)
+}
+
+func (r memLots) Update(lot *pb.Lot) error {
+	return set(r.tx, r.tx.m.lots, lot.Id, lot, true)
+}
+
+func (r memLots) Delete(id string) error {
+	return remove(r.tx, r.tx.m.lots, id)
+}
+
+func (r memLots) ListByProduct(productID string) ([]*pb.Lot, error) {
+	return listIDs(r.tx.m.lots, r.tx.m.lots.lookup("product", productID)), nil
+}
+
+func (r memLots) ListExpiring(before time.Time) ([]*pb.Lot, error) {
+	lots := list(r.tx.m.lots, func(l *pb.Lot) bool {
+		return l.Quantity > 0 && l.ExpireTime != nil && l.ExpireTime.AsTime().Before(before)
+	})
+	slices.SortStableFunc(lots, func(a, b *pb.Lot) int {
+		return a.ExpireTime.AsTime().Compare(b.ExpireTime.AsTime())
+	})
+	return lots, nil
+}
+
+type memSerials struct{ tx *memTx }
+
+func (r memSerials) Get(productID, serial string) (*pb.SerialNumber, error) {
+	return get(r.tx.m.serials, serialKey(productID, serial))
+}
+
+func (r memSerials) Create(serial *pb.SerialNumber) error {
+	return set(r.tx, r.tx.m.serials, serialKey(serial.ProductId, serial.SerialNumber), serial, false)
+}
+
+func (r memSerials) Update(serial *pb.SerialNumber) error {
+	return set(r.tx, r.tx.m.serials, serialKey(serial.ProductId, serial.SerialNumber), serial, true)
+}
+
+type memCounts struct{ tx *memTx }
+
+func (r memCounts) Get(id string) (*pb.CycleCount, error) {
+	return get(r.tx.m.counts, id)
+}
+
+func (r memCounts) Create(count *pb.CycleCount) error {
+	return set(r.tx, r.tx.m.counts, count.Id, count, false)
+}
+
+func (r memCounts) Update(count *pb.CycleCount) error {
+	return set(r.tx, r.tx.m.counts, count.Id, count, true)
+}
+
+func (r memCounts) ListOpen(warehouseID string) ([]*pb.CycleCount, error) {
+	return listIDs(r.tx.m.counts, r.tx.m.counts.lookup("open", warehouseID)), nil
+}
+
+type memHolds struct{ tx *memTx }
+
+func (r memHolds) Get(id string) (*pb.Hold, error) {
+	return get(r.tx.m.holds, id)
+}
+
+func (r memHolds) Create(hold *pb.Hold) error {
+	return set(r.tx, r.tx.m.holds, hold.Id, hold, false)
+}
+
+func (r memHolds) Update(hold *pb.Hold) error {
+	return set(r.tx, r.tx.m.holds, hold.Id, hold, true)
+}
+
+func (r memHolds) ListExpired(before time.Time, limit int) ([]*pb.Hold, error) {
+	holds := listIDs(r.tx.m.holds, r.tx.m.holds.lookup("active", ""))
+	holds = slices.DeleteFunc(holds, func(h *pb.Hold) bool {
+		return h.ExpireTime == nil || !h.ExpireTime.AsTime().Before(before)
+	})
+	slices.SortStableFunc(holds, func(a, b *pb.Hold) int {
+		return a.ExpireTime.AsTime().Compare(b.ExpireTime.AsTime())
+	})
+	if limit > 0 && len(holds) > limit {
+		holds = holds[:limit]
+	}
+	return holds, nil
+}
+
+func (r memHolds) ListActive() ([]*pb.Hold, error) {
+	return listIDs(r.tx.m.holds, r.tx.m.holds.lookup("active", "")), nil
+}
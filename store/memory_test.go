@@ -0,0 +1,12 @@
+package store_test
+
+import (
+	"testing"
+
+	"github.com/kingztech2019/proto_grpc/proto_inventory/store"
+	"github.com/kingztech2019/proto_grpc/proto_inventory/store/storetest"
+)
+
+func TestMemory(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.Store { return store.NewMemory() })
+}
@@ -0,0 +1,245 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// migrations are applied in order, each in its own transaction, and
+// recorded in schema_migrations. Never edit a released migration; append a
+// new one instead.
+//
+// Every table keeps the complete protobuf message in its data column. The
+// other columns mirror message fields so reporting tools can query them.
+var migrations = []string{
+	// 1: products and orders
+	`CREATE TABLE products (
+		id              TEXT PRIMARY KEY,
+		name            TEXT NOT NULL,
+		price           REAL NOT NULL,
+		inventory_level INTEGER NOT NULL,
+		data            BYTEA NOT NULL
+	);
+	CREATE TABLE orders (
+		id         TEXT PRIMARY KEY,
+		product_id TEXT NOT NULL,
+		quantity   INTEGER NOT NULL,
+		order_date TIMESTAMP WITH TIME ZONE,
+		data       BYTEA NOT NULL
+	);
+	CREATE INDEX orders_product_id_idx ON orders (product_id);`,
+
+	// 2: inventory event log; id is the zero-padded sequence
+	`CREATE TABLE inventory_events (
+		id              TEXT PRIMARY KEY,
+		sequence        BIGINT NOT NULL UNIQUE,
+		product_id      TEXT NOT NULL,
+		type            TEXT NOT NULL,
+		inventory_level INTEGER NOT NULL,
+		event_time      TIMESTAMP WITH TIME ZONE NOT NULL,
+		data            BYTEA NOT NULL
+	);`,
+
+	// 3: order lifecycle status
+	`ALTER TABLE orders ADD COLUMN status TEXT NOT NULL DEFAULT 'ORDER_STATUS_UNSPECIFIED';
+	CREATE INDEX orders_status_idx ON orders (status);`,
+
+	// 4: order lines; single-product orders become their first line
+	`CREATE TABLE order_lines (
+		order_id    TEXT NOT NULL,
+		line_number INTEGER NOT NULL,
+		product_id  TEXT NOT NULL,
+		quantity    INTEGER NOT NULL,
+		unit_price  REAL NOT NULL,
+		PRIMARY KEY (order_id, line_number)
+	);
+	CREATE INDEX order_lines_product_id_idx ON order_lines (product_id);
+	INSERT INTO order_lines (order_id, line_number, product_id, quantity, unit_price)
+		SELECT id, 0, product_id, quantity, 0 FROM orders WHERE product_id <> '';`,
+
+	// 5: idempotency keys
+	`CREATE TABLE idempotency_keys (
+		id          TEXT PRIMARY KEY,
+		method      TEXT NOT NULL,
+		expire_time TIMESTAMP WITH TIME ZONE NOT NULL,
+		data        BYTEA NOT NULL
+	);
+	CREATE INDEX idempotency_keys_expire_time_idx ON idempotency_keys (expire_time);`,
+
+	// 6: ListOrders; quantity becomes the total across an order's lines
+	`UPDATE orders SET quantity = (
+		SELECT COALESCE(SUM(quantity), 0) FROM order_lines WHERE order_lines.order_id = orders.id
+	);
+	CREATE INDEX orders_order_date_idx ON orders (order_date, id);`,
+
+	// 7: warehouses and per-warehouse stock
+	`CREATE TABLE warehouses (
+		id   TEXT PRIMARY KEY,
+		name TEXT NOT NULL,
+		data BYTEA NOT NULL
+	);
+	CREATE TABLE stock_levels (
+		id           TEXT PRIMARY KEY,
+		product_id   TEXT NOT NULL,
+		warehouse_id TEXT NOT NULL,
+		quantity     INTEGER NOT NULL,
+		data         BYTEA NOT NULL,
+		UNIQUE (product_id, warehouse_id)
+	);
+	CREATE INDEX stock_levels_warehouse_id_idx ON stock_levels (warehouse_id, product_id);
+	ALTER TABLE orders ADD COLUMN warehouse_id TEXT NOT NULL DEFAULT '';`,
+
+	// 8: stock movement ledger
+	`CREATE TABLE stock_movements (
+		id           TEXT PRIMARY KEY,
+		sequence     BIGINT NOT NULL UNIQUE,
+		product_id   TEXT NOT NULL,
+		warehouse_id TEXT NOT NULL,
+		delta        INTEGER NOT NULL,
+		reason       TEXT NOT NULL,
+		data         BYTEA NOT NULL
+	);
+	CREATE INDEX stock_movements_product_id_idx ON stock_movements (product_id, sequence);
+	CREATE INDEX stock_movements_warehouse_id_idx ON stock_movements (warehouse_id, sequence);`,
+
+	// 9: alert log
+	`CREATE TABLE alerts (
+		id         TEXT PRIMARY KEY,
+		sequence   BIGINT NOT NULL UNIQUE,
+		product_id TEXT NOT NULL,
+		type       TEXT NOT NULL,
+		data       BYTEA NOT NULL
+	);`,
+
+	// 10: stock transfers
+	`CREATE TABLE transfers (
+		id                       TEXT PRIMARY KEY,
+		source_warehouse_id      TEXT NOT NULL,
+		destination_warehouse_id TEXT NOT NULL,
+		status                   TEXT NOT NULL,
+		data                     BYTEA NOT NULL
+	);
+	CREATE INDEX transfers_status_idx ON transfers (status);`,
+
+	// 11: lots
+	`CREATE TABLE lots (
+		id           TEXT PRIMARY KEY,
+		product_id   TEXT NOT NULL,
+		warehouse_id TEXT NOT NULL,
+		lot_number   TEXT NOT NULL,
+		quantity     INTEGER NOT NULL,
+		expire_time  TIMESTAMP WITH TIME ZONE,
+		data         BYTEA NOT NULL
+	);
+	CREATE INDEX lots_product_id_idx ON lots (product_id);
+	CREATE INDEX lots_expire_time_idx ON lots (expire_time);`,
+
+	// 12: serial numbers
+	`CREATE TABLE serial_numbers (
+		id            TEXT PRIMARY KEY,
+		product_id    TEXT NOT NULL,
+		serial_number TEXT NOT NULL,
+		status        TEXT NOT NULL,
+		order_id      TEXT NOT NULL,
+		data          BYTEA NOT NULL,
+		UNIQUE (product_id, serial_number)
+	);
+	CREATE INDEX serial_numbers_order_id_idx ON serial_numbers (order_id);`,
+
+	// 13: cycle counts
+	`CREATE TABLE cycle_counts (
+		id           TEXT PRIMARY KEY,
+		warehouse_id TEXT NOT NULL,
+		status       TEXT NOT NULL,
+		data         BYTEA NOT NULL
+	);
+	CREATE INDEX cycle_counts_warehouse_id_idx ON cycle_counts (warehouse_id, status);`,
+
+	// 14: cart holds
+	`CREATE TABLE holds (
+		id          TEXT PRIMARY KEY,
+		status      TEXT NOT NULL,
+		expire_time TIMESTAMP WITH TIME ZONE NOT NULL,
+		data        BYTEA NOT NULL
+	);
+	CREATE INDEX holds_expire_time_idx ON holds (status, expire_time);`,
+
+	// 15: backorders
+	`ALTER TABLE order_lines ADD COLUMN backordered_quantity INTEGER NOT NULL DEFAULT 0;`,
+
+	// 16: holds without an expire_time never expire. The table is rebuilt
+	// because SQLite cannot drop a NOT NULL constraint in place.
+	`CREATE TABLE holds_v16 (
+		id          TEXT PRIMARY KEY,
+		status      TEXT NOT NULL,
+		expire_time TIMESTAMP WITH TIME ZONE,
+		data        BYTEA NOT NULL
+	);
+	INSERT INTO holds_v16 (id, status, expire_time, data) SELECT id, status, expire_time, data FROM holds;
+	DROP TABLE holds;
+	ALTER TABLE holds_v16 RENAME TO holds;
+	CREATE INDEX holds_expire_time_idx ON holds (status, expire_time);`,
+}
+
+// migrate brings the schema up to the latest version.
+func (s *SQL) migrate(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		applied_at TIMESTAMP WITH TIME ZONE NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("store: create schema_migrations: %w", err)
+	}
+
+	for i, stmt := range migrations {
+		version := i + 1
+		if err := s.applyMigration(ctx, version, stmt); err != nil {
+			return fmt.Errorf("store: migration %d: %w", version, err)
+		}
+	}
+	return nil
+}
+
+// applyMigration runs one migration unless it has already been applied.
+// Servers starting together serialize on the dialect's migration lock, so
+// each migration runs exactly once.
+func (s *SQL) applyMigration(ctx context.Context, version int, stmt string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if s.dialect.migrationLock != "" {
+		if _, err := tx.ExecContext(ctx, s.dialect.migrationLock); err != nil {
+			return err
+		}
+	}
+	var applied int
+	err = tx.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM schema_migrations WHERE version = $1`, version).Scan(&applied)
+	if err != nil {
+		return err
+	}
+	if applied > 0 {
+		return nil
+	}
+	if _, err := tx.ExecContext(ctx, stmt); err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO schema_migrations (version, applied_at) VALUES ($1, $2)`, version, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SchemaVersion returns the number of migrations applied to the database.
+func (s *SQL) SchemaVersion(ctx context.Context) (int, error) {
+	var version sql.NullInt64
+	err := s.db.QueryRowContext(ctx, `SELECT MAX(version) FROM schema_migrations`).Scan(&version)
+	return int(version.Int64), err
+}
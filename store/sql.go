@@ -0,0 +1,862 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	pb "github.com/kingztech2019/proto_grpc/proto_inventory/grpc-inventory-proto"
+	"google.golang.org/protobuf/proto"
+)
+
+// sqlRetries bounds how often an Update is re-run after the database aborts
+// it to keep transactions serializable.
+const sqlRetries = 5
+
+// SQLOptions configures OpenSQL. Zero values keep the database/sql defaults.
+type SQLOptions struct {
+	// Dialect is "postgres" (the default) or "sqlite". SQLite databases
+	// should be opened with an immediate transaction lock and a busy
+	// timeout, e.g. "file:inv.db?_txlock=immediate&_busy_timeout=5000".
+	Dialect string
+
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+// dialect captures the SQL differences between supported databases.
+type dialect struct {
+	// lockRows is appended to SELECTs in read-write transactions.
+	lockRows string
+	// isolation is used for read-write transactions.
+	isolation sql.IsolationLevel
+	// readOnly marks View transactions read-only in the database too.
+	readOnly bool
+	// migrationLock is run at the start of each migration transaction.
+	migrationLock string
+}
+
+var dialects = map[string]dialect{
+	"postgres": {
+		lockRows:      " FOR UPDATE",
+		isolation:     sql.LevelSerializable,
+		readOnly:      true,
+		migrationLock: "SELECT pg_advisory_xact_lock(7426001)",
+	},
+	// SQLite allows a single writer at a time, so transactions are already
+	// serializable and rows need no explicit locks.
+	"sqlite": {},
+}
+
+// SQL is a Store backed by a relational database through database/sql.
+// Reads inside Update lock the rows they return until the transaction
+// ends, so read-modify-write sequences such as stock decrements cannot
+// interleave.
+type SQL struct {
+	db      *sql.DB
+	dialect dialect
+}
+
+// OpenSQL connects with the named database/sql driver, which the caller
+// must have registered, and migrates the schema to the latest version.
+func OpenSQL(ctx context.Context, driverName, dsn string, opts SQLOptions) (*SQL, error) {
+	name := opts.Dialect
+	if name == "" {
+		name = "postgres"
+	}
+	d, ok := dialects[name]
+	if !ok {
+		return nil, fmt.Errorf("store: unsupported SQL dialect %q", name)
+	}
+
+	db, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, err
+	}
+	if opts.MaxOpenConns != 0 {
+		db.SetMaxOpenConns(opts.MaxOpenConns)
+	}
+	if opts.MaxIdleConns != 0 {
+		db.SetMaxIdleConns(opts.MaxIdleConns)
+	}
+	if opts.ConnMaxLifetime != 0 {
+		db.SetConnMaxLifetime(opts.ConnMaxLifetime)
+	}
+	if opts.ConnMaxIdleTime != 0 {
+		db.SetConnMaxIdleTime(opts.ConnMaxIdleTime)
+	}
+
+	s := &SQL{db: db, dialect: d}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err := s.migrate(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// View runs fn in a read-only database transaction.
+func (s *SQL) View(ctx context.Context, fn func(Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: s.dialect.readOnly})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(&sqlTx{ctx: ctx, tx: tx, d: s.dialect}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Update runs fn in a read-write database transaction. When the database
+// aborts the transaction because it conflicted with a concurrent one, fn is
+// run again on a fresh transaction.
+func (s *SQL) Update(ctx context.Context, fn func(Tx) error) error {
+	var err error
+	for attempt := 0; attempt < sqlRetries; attempt++ {
+		err = s.update(ctx, fn)
+		if !isRetryable(err) {
+			return err
+		}
+	}
+	return err
+}
+
+func (s *SQL) update(ctx context.Context, fn func(Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{Isolation: s.dialect.isolation})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(&sqlTx{ctx: ctx, tx: tx, d: s.dialect, writable: true}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Close closes the connection pool.
+func (s *SQL) Close() error {
+	return s.db.Close()
+}
+
+// isRetryable reports serialization failures and deadlocks, which the
+// database resolves by aborting one of the transactions involved.
+func isRetryable(err error) bool {
+	var state interface{ SQLState() string }
+	if errors.As(err, &state) {
+		switch state.SQLState() {
+		case "40001", "40P01":
+			return true
+		}
+	}
+	return false
+}
+
+type sqlTx struct {
+	ctx      context.Context
+	tx       *sql.Tx
+	d        dialect
+	writable bool
+}
+
+func (tx *sqlTx) Products() ProductRepository        { return sqlProducts{tx} }
+func (tx *sqlTx) Orders() OrderRepository            { return sqlOrders{tx} }
+func (tx *sqlTx) Events() EventRepository            { return sqlEvents{tx} }
+func (tx *sqlTx) Idempotency() IdempotencyRepository { return sqlIdempotency{tx} }
+func (tx *sqlTx) Warehouses() WarehouseRepository    { return sqlWarehouses{tx} }
+func (tx *sqlTx) Stock() StockRepository             { return sqlStock{tx} }
+func (tx *sqlTx) Movements() MovementRepository      { return sqlMovements{tx} }
+func (tx *sqlTx) Alerts() AlertRepository            { return sqlAlerts{tx} }
+func (tx *sqlTx) Transfers() TransferRepository      { return sqlTransfers{tx} }
+func (tx *sqlTx) Lots() LotRepository                { return sqlLots{tx} }
+func (tx *sqlTx) Serials() SerialRepository          { return sqlSerials{tx} }
+func (tx *sqlTx) Counts() CountRepository            { return sqlCounts{tx} }
+func (tx *sqlTx) Holds() HoldRepository              { return sqlHolds{tx} }
+
+// sqlTable maps a message type to a table with an id primary key, a data
+// column holding the marshaled message and the mirrored columns listed in
+// columns, whose values come from values.
+type sqlTable[T proto.Message] struct {
+	name    string
+	columns []string
+	values  func(T) []any
+}
+
+func (t sqlTable[T]) decode(data []byte) (T, error) {
+	var zero T
+	v := zero.ProtoReflect().Type().New().Interface().(T)
+	if err := proto.Unmarshal(data, v); err != nil {
+		return zero, fmt.Errorf("store: decode %s: %w", t.name, err)
+	}
+	return v, nil
+}
+
+func (t sqlTable[T]) row(id string, v T) ([]any, error) {
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]any{id}, t.values(v)...), data), nil
+}
+
+func (t sqlTable[T]) get(tx *sqlTx, id string) (T, error) {
+	rows, err := t.query(tx, "WHERE id = $1", id)
+	if err != nil || len(rows) == 0 {
+		var zero T
+		if err == nil {
+			err = ErrNotFound
+		}
+		return zero, err
+	}
+	return rows[0], nil
+}
+
+// query returns the rows matching where, ordered by id. Inside Update the
+// rows stay locked until the transaction ends.
+func (t sqlTable[T]) query(tx *sqlTx, where string, args ...any) ([]T, error) {
+	return t.queryLimit(tx, where, 0, args...)
+}
+
+// queryLimit is query returning at most limit rows, or all if limit is 0.
+func (t sqlTable[T]) queryLimit(tx *sqlTx, where string, limit int, args ...any) ([]T, error) {
+	return t.queryOrdered(tx, where, "id", limit, args...)
+}
+
+// queryOrdered is queryLimit with the rows sorted by orderBy instead of id.
+func (t sqlTable[T]) queryOrdered(tx *sqlTx, where, orderBy string, limit int, args ...any) ([]T, error) {
+	q := fmt.Sprintf("SELECT data FROM %s %s ORDER BY %s", t.name, where, orderBy)
+	if limit > 0 {
+		q += fmt.Sprintf(" LIMIT %d", limit)
+	}
+	if tx.writable {
+		q += tx.d.lockRows
+	}
+	rows, err := tx.tx.QueryContext(tx.ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []T
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		v, err := t.decode(data)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, rows.Err()
+}
+
+func (t sqlTable[T]) insert(tx *sqlTx, id string, v T) error {
+	if !tx.writable {
+		return ErrReadOnly
+	}
+	args, err := t.row(id, v)
+	if err != nil {
+		return err
+	}
+	cols := append(append([]string{"id"}, t.columns...), "data")
+	q := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) ON CONFLICT (id) DO NOTHING",
+		t.name, strings.Join(cols, ", "), placeholders(1, len(cols)))
+	res, err := tx.tx.ExecContext(tx.ctx, q, args...)
+	if err != nil {
+		return err
+	}
+	return expectRow(res, ErrAlreadyExists)
+}
+
+func (t sqlTable[T]) update(tx *sqlTx, id string, v T) error {
+	if !tx.writable {
+		return ErrReadOnly
+	}
+	args, err := t.row(id, v)
+	if err != nil {
+		return err
+	}
+	// The columns take the leading placeholders, in the order row returns
+	// them after id, and id takes the last.
+	sets := make([]string, 0, len(t.columns)+1)
+	for i, c := range append(append([]string{}, t.columns...), "data") {
+		sets = append(sets, fmt.Sprintf("%s = $%d", c, i+1))
+	}
+	q := fmt.Sprintf("UPDATE %s SET %s WHERE id = $%d", t.name, strings.Join(sets, ", "), len(sets)+1)
+	res, err := tx.tx.ExecContext(tx.ctx, q, append(args[1:], args[0])...)
+	if err != nil {
+		return err
+	}
+	return expectRow(res, ErrNotFound)
+}
+
+func (t sqlTable[T]) delete(tx *sqlTx, id string) error {
+	if !tx.writable {
+		return ErrReadOnly
+	}
+	res, err := tx.tx.ExecContext(tx.ctx, fmt.Sprintf("DELETE FROM %s WHERE id = $1", t.name), id)
+	if err != nil {
+		return err
+	}
+	return expectRow(res, ErrNotFound)
+}
+
+// expectRow returns errNone if the statement affected no rows.
+func expectRow(res sql.Result, errNone error) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return errNone
+	}
+	return nil
+}
+
+// placeholders returns "$from, ..., $(from+n-1)".
+func placeholders(from, n int) string {
+	ps := make([]string, n)
+	for i := range ps {
+		ps[i] = fmt.Sprintf("$%d", from+i)
+	}
+	return strings.Join(ps, ", ")
+}
+
+var productsTable = sqlTable[*pb.Product]{
+	name:    "products",
+	columns: []string{"name", "price", "inventory_level"},
+	values: func(p *pb.Product) []any {
+		return []any{p.Name, p.Price, p.InventoryLevel}
+	},
+}
+
+var ordersTable = sqlTable[*pb.Order]{
+	name:    "orders",
+	columns: []string{"product_id", "quantity", "order_date", "status", "warehouse_id"},
+	values: func(o *pb.Order) []any {
+		var date sql.NullTime
+		if o.OrderDate != nil {
+			date = sql.NullTime{Time: o.OrderDate.AsTime(), Valid: true}
+		}
+		return []any{o.ProductId, orderQuantity(o), date, o.Status.String(), o.WarehouseId}
+	},
+}
+
+type sqlProducts struct{ tx *sqlTx }
+
+func (r sqlProducts) Get(id string) (*pb.Product, error) {
+	return productsTable.get(r.tx, id)
+}
+
+func (r sqlProducts) Create(product *pb.Product) error {
+	return productsTable.insert(r.tx, product.Id, product)
+}
+
+func (r sqlProducts) Update(product *pb.Product) error {
+	return productsTable.update(r.tx, product.Id, product)
+}
+
+func (r sqlProducts) Delete(id string) error {
+	return productsTable.delete(r.tx, id)
+}
+
+func (r sqlProducts) List() ([]*pb.Product, error) {
+	return productsTable.query(r.tx, "")
+}
+
+type sqlOrders struct{ tx *sqlTx }
+
+func (r sqlOrders) Get(id string) (*pb.Order, error) {
+	return ordersTable.get(r.tx, id)
+}
+
+func (r sqlOrders) Create(order *pb.Order) error {
+	if err := ordersTable.insert(r.tx, order.Id, order); err != nil {
+		return err
+	}
+	return r.writeLines(order)
+}
+
+func (r sqlOrders) Update(order *pb.Order) error {
+	if err := ordersTable.update(r.tx, order.Id, order); err != nil {
+		return err
+	}
+	return r.writeLines(order)
+}
+
+func (r sqlOrders) Delete(id string) error {
+	if err := ordersTable.delete(r.tx, id); err != nil {
+		return err
+	}
+	_, err := r.tx.tx.ExecContext(r.tx.ctx, `DELETE FROM order_lines WHERE order_id = $1`, id)
+	return err
+}
+
+func (r sqlOrders) ListByProduct(productID string) ([]*pb.Order, error) {
+	return ordersTable.query(r.tx,
+		"WHERE id IN (SELECT order_id FROM order_lines WHERE product_id = $1)", productID)
+}
+
+func (r sqlOrders) Query(q OrderQuery) ([]*pb.Order, error) {
+	conds := []string{"order_date IS NOT NULL"}
+	var args []any
+	// arg adds a bind parameter, numbered in order of appearance.
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	var lines []string
+	if q.ProductID != "" {
+		lines = append(lines, "product_id = "+arg(q.ProductID))
+	}
+	if q.Backordered {
+		lines = append(lines, "backordered_quantity > 0")
+	}
+	if len(lines) > 0 {
+		conds = append(conds, "id IN (SELECT order_id FROM order_lines WHERE "+strings.Join(lines, " AND ")+")")
+	}
+	if len(q.Statuses) > 0 {
+		ps := make([]string, len(q.Statuses))
+		for i, status := range q.Statuses {
+			ps[i] = arg(status.String())
+		}
+		conds = append(conds, "status IN ("+strings.Join(ps, ", ")+")")
+	}
+	if !q.From.IsZero() {
+		conds = append(conds, "order_date >= "+arg(q.From.UTC()))
+	}
+	if !q.To.IsZero() {
+		conds = append(conds, "order_date < "+arg(q.To.UTC()))
+	}
+	if q.MinQuantity != 0 {
+		conds = append(conds, "quantity >= "+arg(q.MinQuantity))
+	}
+	if q.MaxQuantity != 0 {
+		conds = append(conds, "quantity <= "+arg(q.MaxQuantity))
+	}
+	if q.AfterID != "" {
+		date := arg(q.AfterDate.UTC())
+		conds = append(conds, fmt.Sprintf("(order_date > %s OR order_date = %s AND id > %s)", date, date, arg(q.AfterID)))
+	}
+	where := "WHERE " + strings.Join(conds, " AND ")
+	return ordersTable.queryOrdered(r.tx, where, "order_date, id", q.Limit, args...)
+}
+
+// writeLines replaces the order_lines rows mirroring order's lines.
+func (r sqlOrders) writeLines(order *pb.Order) error {
+	_, err := r.tx.tx.ExecContext(r.tx.ctx, `DELETE FROM order_lines WHERE order_id = $1`, order.Id)
+	if err != nil {
+		return err
+	}
+	for i, line := range orderLines(order) {
+		_, err := r.tx.tx.ExecContext(r.tx.ctx,
+			`INSERT INTO order_lines (order_id, line_number, product_id, quantity, unit_price, backordered_quantity)
+			VALUES ($1, $2, $3, $4, $5, $6)`,
+			order.Id, i, line.ProductId, line.Quantity, line.UnitPrice, line.BackorderedQuantity)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var eventsTable = sqlTable[*pb.InventoryEvent]{
+	name:    "inventory_events",
+	columns: []string{"sequence", "product_id", "type", "inventory_level", "event_time"},
+	values: func(e *pb.InventoryEvent) []any {
+		return []any{int64(e.Sequence), e.ProductId, e.Type.String(), e.InventoryLevel, e.Time.AsTime()}
+	},
+}
+
+type sqlEvents struct{ tx *sqlTx }
+
+// Append takes the next sequence from MAX(sequence). Two transactions
+// appending at once conflict, and the serializable retry in Update makes the
+// later one start over, so sequence order matches commit order.
+func (r sqlEvents) Append(event *pb.InventoryEvent) error {
+	if !r.tx.writable {
+		return ErrReadOnly
+	}
+	_, last, err := r.Bounds()
+	if err != nil {
+		return err
+	}
+	event.Sequence = last + 1
+	return eventsTable.insert(r.tx, eventKey(event.Sequence), event)
+}
+
+func (r sqlEvents) ListAfter(after uint64, limit int) ([]*pb.InventoryEvent, error) {
+	return eventsTable.queryLimit(r.tx, "WHERE sequence > $1", limit, int64(after))
+}
+
+func (r sqlEvents) Bounds() (first, last uint64, err error) {
+	var lo, hi sql.NullInt64
+	err = r.tx.tx.QueryRowContext(r.tx.ctx,
+		"SELECT MIN(sequence), MAX(sequence) FROM inventory_events").Scan(&lo, &hi)
+	return uint64(lo.Int64), uint64(hi.Int64), err
+}
+
+func (r sqlEvents) DeleteBefore(seq uint64) error {
+	if !r.tx.writable {
+		return ErrReadOnly
+	}
+	_, last, err := r.Bounds()
+	if err != nil {
+		return err
+	}
+	_, err = r.tx.tx.ExecContext(r.tx.ctx,
+		"DELETE FROM inventory_events WHERE sequence < $1", int64(min(seq, last)))
+	return err
+}
+
+var idempotencyTable = sqlTable[*pb.IdempotencyRecord]{
+	name:    "idempotency_keys",
+	columns: []string{"method", "expire_time"},
+	values: func(r *pb.IdempotencyRecord) []any {
+		return []any{r.Method, r.ExpireTime.AsTime()}
+	},
+}
+
+type sqlIdempotency struct{ tx *sqlTx }
+
+func (r sqlIdempotency) Get(key string) (*pb.IdempotencyRecord, error) {
+	return idempotencyTable.get(r.tx, key)
+}
+
+func (r sqlIdempotency) Create(record *pb.IdempotencyRecord) error {
+	return idempotencyTable.insert(r.tx, record.Key, record)
+}
+
+func (r sqlIdempotency) Update(record *pb.IdempotencyRecord) error {
+	return idempotencyTable.update(r.tx, record.Key, record)
+}
+
+func (r sqlIdempotency) DeleteExpired(now time.Time) (int, error) {
+	if !r.tx.writable {
+		return 0, ErrReadOnly
+	}
+	res, err := r.tx.tx.ExecContext(r.tx.ctx,
+		"DELETE FROM idempotency_keys WHERE expire_time < $1", now.UTC())
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+var warehousesTable = sqlTable[*pb.Warehouse]{
+	name:    "warehouses",
+	columns: []string{"name"},
+	values: func(w *pb.Warehouse) []any {
+		return []any{w.Name}
+	},
+}
+
+type sqlWarehouses struct{ tx *sqlTx }
+
+func (r sqlWarehouses) Get(id string) (*pb.Warehouse, error) {
+	return warehousesTable.get(r.tx, id)
+}
+
+func (r sqlWarehouses) Create(warehouse *pb.Warehouse) error {
+	return warehousesTable.insert(r.tx, warehouse.Id, warehouse)
+}
+
+func (r sqlWarehouses) Delete(id string) error {
+	return warehousesTable.delete(r.tx, id)
+}
+
+func (r sqlWarehouses) List() ([]*pb.Warehouse, error) {
+	return warehousesTable.query(r.tx, "")
+}
+
+var stockTable = sqlTable[*pb.StockLevel]{
+	name:    "stock_levels",
+	columns: []string{"product_id", "warehouse_id", "quantity"},
+	values: func(l *pb.StockLevel) []any {
+		return []any{l.ProductId, l.WarehouseId, l.Quantity}
+	},
+}
+
+type sqlStock struct{ tx *sqlTx }
+
+func (r sqlStock) Get(productID, warehouseID string) (*pb.StockLevel, error) {
+	return stockTable.get(r.tx, stockKey(productID, warehouseID))
+}
+
+func (r sqlStock) Put(level *pb.StockLevel) error {
+	key := stockKey(level.ProductId, level.WarehouseId)
+	err := stockTable.update(r.tx, key, level)
+	if errors.Is(err, ErrNotFound) {
+		return stockTable.insert(r.tx, key, level)
+	}
+	return err
+}
+
+func (r sqlStock) Delete(productID, warehouseID string) error {
+	return stockTable.delete(r.tx, stockKey(productID, warehouseID))
+}
+
+func (r sqlStock) ListByProduct(productID string) ([]*pb.StockLevel, error) {
+	return stockTable.queryOrdered(r.tx, "WHERE product_id = $1", "warehouse_id", 0, productID)
+}
+
+func (r sqlStock) ListByWarehouse(warehouseID string) ([]*pb.StockLevel, error) {
+	return stockTable.queryOrdered(r.tx, "WHERE warehouse_id = $1", "product_id", 0, warehouseID)
+}
+
+var movementsTable = sqlTable[*pb.StockMovement]{
+	name:    "stock_movements",
+	columns: []string{"sequence", "product_id", "warehouse_id", "delta", "reason"},
+	values: func(m *pb.StockMovement) []any {
+		return []any{int64(m.Sequence), m.ProductId, m.WarehouseId, m.Delta, m.Reason.String()}
+	},
+}
+
+type sqlMovements struct{ tx *sqlTx }
+
+// Append takes the next sequence from MAX(sequence); concurrent appends
+// conflict and are retried like event appends.
+func (r sqlMovements) Append(movement *pb.StockMovement) error {
+	if !r.tx.writable {
+		return ErrReadOnly
+	}
+	var last sql.NullInt64
+	err := r.tx.tx.QueryRowContext(r.tx.ctx, "SELECT MAX(sequence) FROM stock_movements").Scan(&last)
+	if err != nil {
+		return err
+	}
+	movement.Sequence = uint64(last.Int64) + 1
+	return movementsTable.insert(r.tx, eventKey(movement.Sequence), movement)
+}
+
+func (r sqlMovements) Query(q MovementQuery) ([]*pb.StockMovement, error) {
+	conds := []string{"sequence > $1"}
+	args := []any{int64(q.AfterSequence)}
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	if q.ProductID != "" {
+		conds = append(conds, "product_id = "+arg(q.ProductID))
+	}
+	if q.WarehouseID != "" {
+		conds = append(conds, "warehouse_id = "+arg(q.WarehouseID))
+	}
+	if q.Reason != pb.StockMovementReason_STOCK_MOVEMENT_REASON_UNSPECIFIED {
+		conds = append(conds, "reason = "+arg(q.Reason.String()))
+	}
+	where := "WHERE " + strings.Join(conds, " AND ")
+	return movementsTable.queryOrdered(r.tx, where, "sequence", q.Limit, args...)
+}
+
+func (r sqlMovements) Totals(productID string) (map[string]int32, error) {
+	rows, err := r.tx.tx.QueryContext(r.tx.ctx,
+		"SELECT warehouse_id, SUM(delta) FROM stock_movements WHERE product_id = $1 GROUP BY warehouse_id", productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	totals := make(map[string]int32)
+	for rows.Next() {
+		var warehouseID string
+		var total int64
+		if err := rows.Scan(&warehouseID, &total); err != nil {
+			return nil, err
+		}
+		totals[warehouseID] = int32(total)
+	}
+	return totals, rows.Err()
+}
+
+var alertsTable = sqlTable[*pb.Alert]{
+	name:    "alerts",
+	columns: []string{"sequence", "product_id", "type"},
+	values: func(a *pb.Alert) []any {
+		return []any{int64(a.Sequence), a.ProductId, a.Type.String()}
+	},
+}
+
+type sqlAlerts struct{ tx *sqlTx }
+
+func (r sqlAlerts) Append(alert *pb.Alert) error {
+	if !r.tx.writable {
+		return ErrReadOnly
+	}
+	last, err := r.Last()
+	if err != nil {
+		return err
+	}
+	alert.Sequence = last + 1
+	return alertsTable.insert(r.tx, eventKey(alert.Sequence), alert)
+}
+
+func (r sqlAlerts) ListAfter(after uint64, limit int) ([]*pb.Alert, error) {
+	return alertsTable.queryOrdered(r.tx, "WHERE sequence > $1", "sequence", limit, int64(after))
+}
+
+func (r sqlAlerts) Last() (uint64, error) {
+	var last sql.NullInt64
+	err := r.tx.tx.QueryRowContext(r.tx.ctx, "SELECT MAX(sequence) FROM alerts").Scan(&last)
+	return uint64(last.Int64), err
+}
+
+var transfersTable = sqlTable[*pb.Transfer]{
+	name:    "transfers",
+	columns: []string{"source_warehouse_id", "destination_warehouse_id", "status"},
+	values: func(t *pb.Transfer) []any {
+		return []any{t.SourceWarehouseId, t.DestinationWarehouseId, t.Status.String()}
+	},
+}
+
+type sqlTransfers struct{ tx *sqlTx }
+
+func (r sqlTransfers) Get(id string) (*pb.Transfer, error) {
+	return transfersTable.get(r.tx, id)
+}
+
+func (r sqlTransfers) Create(transfer *pb.Transfer) error {
+	return transfersTable.insert(r.tx, transfer.Id, transfer)
+}
+
+func (r sqlTransfers) Update(transfer *pb.Transfer) error {
+	return transfersTable.update(r.tx, transfer.Id, transfer)
+}
+
+func (r sqlTransfers) ListInTransit() ([]*pb.Transfer, error) {
+	return transfersTable.query(r.tx, "WHERE status = $1", pb.TransferStatus_TRANSFER_STATUS_IN_TRANSIT.String())
+}
+
+var lotsTable = sqlTable[*pb.Lot]{
+	name:    "lots",
+	columns: []string{"product_id", "warehouse_id", "lot_number", "quantity", "expire_time"},
+	values: func(l *pb.Lot) []any {
+		var expire any
+		if l.ExpireTime != nil {
+			expire = l.ExpireTime.AsTime()
+		}
+		return []any{l.ProductId, l.WarehouseId, l.LotNumber, l.Quantity, expire}
+	},
+}
+
+type sqlLots struct{ tx *sqlTx }
+
+func (r sqlLots) Get(id string) (*pb.Lot, error) {
+	return lotsTable.get(r.tx, id)
+}
+
+func (r sqlLots) Create(lot *pb.Lot) error {
+	return lotsTable.insert(r.tx, lot.Id, lot)
+}
+
+func (r sqlLots) Update(lot *pb.Lot) error {
+	return lotsTable.update(r.tx, lot.Id, lot)
+}
+
+func (r sqlLots) Delete(id string) error {
+	return lotsTable.delete(r.tx, id)
+}
+
+func (r sqlLots) ListByProduct(productID string) ([]*pb.Lot, error) {
+	return lotsTable.query(r.tx, "WHERE product_id = $1", productID)
+}
+
+func (r sqlLots) ListExpiring(before time.Time) ([]*pb.Lot, error) {
+	return lotsTable.queryOrdered(r.tx, "WHERE quantity > 0 AND expire_time < $1", "expire_time, id", 0, before.UTC())
+}
+
+var serialsTable = sqlTable[*pb.SerialNumber]{
+	name:    "serial_numbers",
+	columns: []string{"product_id", "serial_number", "status", "order_id"},
+	values: func(s *pb.SerialNumber) []any {
+		return []any{s.ProductId, s.SerialNumber, s.Status.String(), s.OrderId}
+	},
+}
+
+type sqlSerials struct{ tx *sqlTx }
+
+func (r sqlSerials) Get(productID, serial string) (*pb.SerialNumber, error) {
+	return serialsTable.get(r.tx, serialKey(productID, serial))
+}
+
+func (r sqlSerials) Create(serial *pb.SerialNumber) error {
+	return serialsTable.insert(r.tx, serialKey(serial.ProductId, serial.SerialNumber), serial)
+}
+
+func (r sqlSerials) Update(serial *pb.SerialNumber) error {
+	return serialsTable.update(r.tx, serialKey(serial.ProductId, serial.SerialNumber), serial)
+}
+
+var countsTable = sqlTable[*pb.CycleCount]{
+	name:    "cycle_counts",
+	columns: []string{"warehouse_id", "status"},
+	values: func(c *pb.CycleCount) []any {
+		return []any{c.WarehouseId, c.Status.String()}
+	},
+}
+
+type sqlCounts struct{ tx *sqlTx }
+
+func (r sqlCounts) Get(id string) (*pb.CycleCount, error) {
+	return countsTable.get(r.tx, id)
+}
+
+func (r sqlCounts) Create(count *pb.CycleCount) error {
+	return countsTable.insert(r.tx, count.Id, count)
+}
+
+func (r sqlCounts) Update(count *pb.CycleCount) error {
+	return countsTable.update(r.tx, count.Id, count)
+}
+
+func (r sqlCounts) ListOpen(warehouseID string) ([]*pb.CycleCount, error) {
+	return countsTable.query(r.tx, "WHERE warehouse_id = $1 AND status = $2",
+		warehouseID, pb.CountStatus_COUNT_STATUS_OPEN.String())
+}
+
+var holdsTable = sqlTable[*pb.Hold]{
+	name:    "holds",
+	columns: []string{"status", "expire_time"},
+	values: func(h *pb.Hold) []any {
+		var expire any
+		if h.ExpireTime != nil {
+			expire = h.ExpireTime.AsTime()
+		}
+		return []any{h.Status.String(), expire}
+	},
+}
+
+type sqlHolds struct{ tx *sqlTx }
+
+func (r sqlHolds) Get(id string) (*pb.Hold, error) {
+	return holdsTable.get(r.tx, id)
+}
+
+func (r sqlHolds) Create(hold *pb.Hold) error {
+	return holdsTable.insert(r.tx, hold.Id, hold)
+}
+
+func (r sqlHolds) Update(hold *pb.Hold) error {
+	return holdsTable.update(r.tx, hold.Id, hold)
+}
+
+func (r sqlHolds) ListExpired(before time.Time, limit int) ([]*pb.Hold, error) {
+	return holdsTable.queryOrdered(r.tx, "WHERE status = $1 AND expire_time < $2", "expire_time, id", limit,
+		pb.HoldStatus_HOLD_STATUS_ACTIVE.String(), before.UTC())
+}
+
+func (r sqlHolds) ListActive() ([]*pb.Hold, error) {
+	return holdsTable.query(r.tx, "WHERE status = $1", pb.HoldStatus_HOLD_STATUS_ACTIVE.String())
+}
@@ -0,0 +1,342 @@
+// Package store defines the persistence interfaces used by the inventory
+// server, so handlers can be backed by memory, disk or a database without
+// changing.
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	pb "github.com/kingztech2019/proto_grpc/proto_inventory/grpc-inventory-proto"
+)
+
+var (
+	// ErrNotFound is returned when a record does not exist.
+	ErrNotFound = errors.New("store: not found")
+	// ErrAlreadyExists is returned when creating a record whose ID is taken.
+	ErrAlreadyExists = errors.New("store: already exists")
+	// ErrReadOnly is returned when writing inside a View transaction.
+	ErrReadOnly = errors.New("store: read-only transaction")
+)
+
+// Store runs transactions over the inventory data.
+//
+// Transactions are serializable: an Update sees no concurrent writes and its
+// changes become visible to others all at once when it commits.
+type Store interface {
+	// View runs fn in a read-only transaction.
+	View(ctx context.Context, fn func(Tx) error) error
+	// Update runs fn in a read-write transaction. The changes are committed
+	// if fn returns nil and discarded otherwise; fn's error is returned
+	// unchanged.
+	Update(ctx context.Context, fn func(Tx) error) error
+	// Close releases the resources held by the store.
+	Close() error
+}
+
+// Tx gives access to the repositories inside a transaction. A Tx must not
+// be used after the function it was passed to returns.
+type Tx interface {
+	Products() ProductRepository
+	Orders() OrderRepository
+	Events() EventRepository
+	Idempotency() IdempotencyRepository
+	Warehouses() WarehouseRepository
+	Stock() StockRepository
+	Movements() MovementRepository
+	Alerts() AlertRepository
+	Transfers() TransferRepository
+	Lots() LotRepository
+	Serials() SerialRepository
+	Counts() CountRepository
+	Holds() HoldRepository
+}
+
+// ProductRepository stores products keyed by Product.id.
+//
+// Messages passed in are copied and messages returned belong to the caller,
+// so neither side may observe the other's later modifications.
+type ProductRepository interface {
+	// Get returns the product with the given ID or ErrNotFound.
+	Get(id string) (*pb.Product, error)
+	// Create stores a new product or returns ErrAlreadyExists.
+	Create(product *pb.Product) error
+	// Update replaces an existing product or returns ErrNotFound.
+	Update(product *pb.Product) error
+	// Delete removes a product or returns ErrNotFound.
+	Delete(id string) error
+	// List returns every product ordered by ID.
+	List() ([]*pb.Product, error)
+}
+
+// OrderRepository stores orders keyed by Order.id.
+//
+// The same copy semantics as ProductRepository apply.
+type OrderRepository interface {
+	// Get returns the order with the given ID or ErrNotFound.
+	Get(id string) (*pb.Order, error)
+	// Create stores a new order or returns ErrAlreadyExists.
+	Create(order *pb.Order) error
+	// Update replaces an existing order or returns ErrNotFound.
+	Update(order *pb.Order) error
+	// Delete removes an order or returns ErrNotFound.
+	Delete(id string) error
+	// ListByProduct returns the orders with a line for a product, ordered
+	// by ID.
+	ListByProduct(productID string) ([]*pb.Order, error)
+	// Query returns the orders matching q ordered by order_date, then ID.
+	// The product and status filters are answered from indexes. Orders
+	// without an order_date are never returned.
+	Query(q OrderQuery) ([]*pb.Order, error)
+}
+
+// OrderQuery selects orders for OrderRepository.Query. Zero-valued fields
+// do not filter.
+type OrderQuery struct {
+	// ProductID matches orders with a line for the product.
+	ProductID string
+	// Backordered matches orders with backordered units, of ProductID when
+	// it is set.
+	Backordered bool
+	// Statuses matches orders in any of the listed statuses.
+	Statuses []pb.OrderStatus
+	// From and To bound order_date to [From, To).
+	From, To time.Time
+	// MinQuantity and MaxQuantity bound the total units across all lines,
+	// inclusively.
+	MinQuantity, MaxQuantity int32
+	// AfterDate and AfterID resume after the last order of a previous
+	// page; AfterID is empty on the first page.
+	AfterDate time.Time
+	AfterID   string
+	// Limit caps the number of orders returned; 0 returns them all.
+	Limit int
+}
+
+// isBackordered reports whether the order has backordered units of the
+// product, or of any product if productID is empty.
+func isBackordered(o *pb.Order, productID string) bool {
+	for _, line := range orderLines(o) {
+		if line.BackorderedQuantity > 0 && (productID == "" || line.ProductId == productID) {
+			return true
+		}
+	}
+	return false
+}
+
+// orderQuantity returns the total units across the order's lines.
+func orderQuantity(o *pb.Order) int32 {
+	var n int32
+	for _, line := range orderLines(o) {
+		n += line.Quantity
+	}
+	return n
+}
+
+// orderLines returns the order's lines, treating an order written before
+// lines existed as a single line for its product_id.
+func orderLines(o *pb.Order) []*pb.OrderLine {
+	if len(o.Lines) == 0 && o.ProductId != "" {
+		return []*pb.OrderLine{{ProductId: o.ProductId, Quantity: o.Quantity}}
+	}
+	return o.Lines
+}
+
+// EventRepository is the append-only log of inventory events, ordered by
+// InventoryEvent.sequence. Sequences are assigned at append time, increase
+// by one per event and are never reused. Because appends happen inside
+// transactions, sequence order is also commit order.
+type EventRepository interface {
+	// Append stores event under the next sequence number and sets its
+	// sequence field.
+	Append(event *pb.InventoryEvent) error
+	// ListAfter returns up to limit events with a sequence greater than
+	// after, in sequence order. A limit of 0 returns them all.
+	ListAfter(after uint64, limit int) ([]*pb.InventoryEvent, error)
+	// Bounds returns the first and last stored sequence numbers, or zeros
+	// if the log is empty.
+	Bounds() (first, last uint64, err error)
+	// DeleteBefore removes the events with a sequence lower than seq. The
+	// last event is always kept so numbering survives a restart.
+	DeleteBefore(seq uint64) error
+}
+
+// IdempotencyRepository stores the outcome of requests made with an
+// idempotency key, keyed by IdempotencyRecord.key.
+//
+// The same copy semantics as ProductRepository apply.
+type IdempotencyRepository interface {
+	// Get returns the record for key or ErrNotFound. Expired records are
+	// returned until DeleteExpired removes them.
+	Get(key string) (*pb.IdempotencyRecord, error)
+	// Create stores a new record or returns ErrAlreadyExists.
+	Create(record *pb.IdempotencyRecord) error
+	// Update replaces an existing record or returns ErrNotFound.
+	Update(record *pb.IdempotencyRecord) error
+	// DeleteExpired removes the records whose expire_time is before now and
+	// returns how many were removed.
+	DeleteExpired(now time.Time) (int, error)
+}
+
+// WarehouseRepository stores warehouses keyed by Warehouse.id.
+//
+// The same copy semantics as ProductRepository apply.
+type WarehouseRepository interface {
+	// Get returns the warehouse with the given ID or ErrNotFound.
+	Get(id string) (*pb.Warehouse, error)
+	// Create stores a new warehouse or returns ErrAlreadyExists.
+	Create(warehouse *pb.Warehouse) error
+	// Delete removes a warehouse or returns ErrNotFound.
+	Delete(id string) error
+	// List returns every warehouse ordered by ID.
+	List() ([]*pb.Warehouse, error)
+}
+
+// StockRepository stores per-warehouse stock levels keyed by product and
+// warehouse.
+//
+// The same copy semantics as ProductRepository apply.
+type StockRepository interface {
+	// Get returns the stock of a product at a warehouse or ErrNotFound.
+	Get(productID, warehouseID string) (*pb.StockLevel, error)
+	// Put creates or replaces a stock level.
+	Put(level *pb.StockLevel) error
+	// Delete removes a stock level or returns ErrNotFound.
+	Delete(productID, warehouseID string) error
+	// ListByProduct returns a product's stock levels ordered by warehouse ID.
+	ListByProduct(productID string) ([]*pb.StockLevel, error)
+	// ListByWarehouse returns a warehouse's stock levels ordered by product
+	// ID.
+	ListByWarehouse(warehouseID string) ([]*pb.StockLevel, error)
+}
+
+// stockKey is the row ID of a stock level. The length prefix keeps keys
+// unambiguous whatever characters the IDs contain.
+func stockKey(productID, warehouseID string) string {
+	return fmt.Sprintf("%d:%s/%s", len(productID), productID, warehouseID)
+}
+
+// MovementRepository is the append-only stock ledger, ordered by
+// StockMovement.sequence. Sequences are assigned at append time and
+// movements are never changed or removed.
+type MovementRepository interface {
+	// Append stores movement under the next sequence number and sets its
+	// sequence field.
+	Append(movement *pb.StockMovement) error
+	// Query returns the movements matching q in sequence order.
+	Query(q MovementQuery) ([]*pb.StockMovement, error)
+	// Totals returns the sum of a product's movement deltas per warehouse.
+	Totals(productID string) (map[string]int32, error)
+}
+
+// MovementQuery selects movements for MovementRepository.Query. Zero-valued
+// fields do not filter.
+type MovementQuery struct {
+	ProductID   string
+	WarehouseID string
+	Reason      pb.StockMovementReason
+	// AfterSequence resumes after the last movement of a previous page.
+	AfterSequence uint64
+	// Limit caps the number of movements returned; 0 returns them all.
+	Limit int
+}
+
+// AlertRepository is the append-only log of alerts, ordered by
+// Alert.sequence. Like the event log, sequences are assigned at append time
+// and follow commit order. Alerts are rare and never removed.
+type AlertRepository interface {
+	// Append stores alert under the next sequence number and sets its
+	// sequence field.
+	Append(alert *pb.Alert) error
+	// ListAfter returns up to limit alerts with a sequence greater than
+	// after, in sequence order. A limit of 0 returns them all.
+	ListAfter(after uint64, limit int) ([]*pb.Alert, error)
+	// Last returns the last stored sequence number, or 0 if there is none.
+	Last() (uint64, error)
+}
+
+// TransferRepository stores stock transfers keyed by Transfer.id.
+//
+// The same copy semantics as ProductRepository apply.
+type TransferRepository interface {
+	// Get returns the transfer with the given ID or ErrNotFound.
+	Get(id string) (*pb.Transfer, error)
+	// Create stores a new transfer or returns ErrAlreadyExists.
+	Create(transfer *pb.Transfer) error
+	// Update replaces an existing transfer or returns ErrNotFound.
+	Update(transfer *pb.Transfer) error
+	// ListInTransit returns the transfers still in transit, ordered by ID.
+	ListInTransit() ([]*pb.Transfer, error)
+}
+
+// LotRepository stores lots keyed by Lot.id.
+//
+// The same copy semantics as ProductRepository apply.
+type LotRepository interface {
+	// Get returns the lot with the given ID or ErrNotFound.
+	Get(id string) (*pb.Lot, error)
+	// Create stores a new lot or returns ErrAlreadyExists.
+	Create(lot *pb.Lot) error
+	// Update replaces an existing lot or returns ErrNotFound.
+	Update(lot *pb.Lot) error
+	// Delete removes a lot or returns ErrNotFound.
+	Delete(id string) error
+	// ListByProduct returns a product's lots in every warehouse, ordered by
+	// ID.
+	ListByProduct(productID string) ([]*pb.Lot, error)
+	// ListExpiring returns the lots that still hold stock and expire before
+	// t, ordered by expire_time, then ID.
+	ListExpiring(before time.Time) ([]*pb.Lot, error)
+}
+
+// SerialRepository stores serial numbers keyed by product and serial.
+//
+// The same copy semantics as ProductRepository apply.
+type SerialRepository interface {
+	// Get returns a product's serial number or ErrNotFound.
+	Get(productID, serial string) (*pb.SerialNumber, error)
+	// Create stores a new serial number or returns ErrAlreadyExists.
+	Create(serial *pb.SerialNumber) error
+	// Update replaces an existing serial number or returns ErrNotFound.
+	Update(serial *pb.SerialNumber) error
+}
+
+// CountRepository stores cycle counts keyed by CycleCount.id.
+//
+// The same copy semantics as ProductRepository apply.
+type CountRepository interface {
+	// Get returns the count with the given ID or ErrNotFound.
+	Get(id string) (*pb.CycleCount, error)
+	// Create stores a new count or returns ErrAlreadyExists.
+	Create(count *pb.CycleCount) error
+	// Update replaces an existing count or returns ErrNotFound.
+	Update(count *pb.CycleCount) error
+	// ListOpen returns the counts at a warehouse that are still open,
+	// ordered by ID.
+	ListOpen(warehouseID string) ([]*pb.CycleCount, error)
+}
+
+// HoldRepository stores cart holds keyed by Hold.id.
+//
+// The same copy semantics as ProductRepository apply.
+type HoldRepository interface {
+	// Get returns the hold with the given ID or ErrNotFound.
+	Get(id string) (*pb.Hold, error)
+	// Create stores a new hold or returns ErrAlreadyExists.
+	Create(hold *pb.Hold) error
+	// Update replaces an existing hold or returns ErrNotFound.
+	Update(hold *pb.Hold) error
+	// ListExpired returns up to limit active holds whose expire_time is
+	// before t, ordered by expire_time, then ID. A limit of 0 returns them
+	// all. Holds without an expire_time never expire.
+	ListExpired(before time.Time, limit int) ([]*pb.Hold, error)
+	// ListActive returns every active hold, ordered by ID.
+	ListActive() ([]*pb.Hold, error)
+}
+
+// serialKey is the row ID of a serial number, built like stockKey.
+func serialKey(productID, serial string) string {
+	return stockKey(productID, serial)
+}
@@ -0,0 +1,1068 @@
+// Package storetest is a conformance suite for store.Store implementations.
+// Every backend is expected to pass it:
+//
+//	func TestConformance(t *testing.T) {
+//		storetest.Run(t, func(t *testing.T) store.Store { return newBackend(t) })
+//	}
+package storetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	pb "github.com/kingztech2019/proto_grpc/proto_inventory/grpc-inventory-proto"
+	"github.com/kingztech2019/proto_grpc/proto_inventory/store"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Run exercises a store implementation. newStore must return an empty store
+// for each call; Run closes it when the subtest finishes.
+func Run(t *testing.T, newStore func(t *testing.T) store.Store) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, s store.Store)
+	}{
+		{"ProductCRUD", testProductCRUD},
+		{"ProductList", testProductList},
+		{"OrderCRUD", testOrderCRUD},
+		{"OrderListByProduct", testOrderListByProduct},
+		{"OrderQuery", testOrderQuery},
+		{"Rollback", testRollback},
+		{"ReadOnlyView", testReadOnlyView},
+		{"CopySemantics", testCopySemantics},
+		{"SerializableUpdates", testSerializableUpdates},
+		{"EventLog", testEventLog},
+		{"ConcurrentEventReads", testConcurrentEventReads},
+		{"Idempotency", testIdempotency},
+		{"Warehouses", testWarehouses},
+		{"StockLevels", testStockLevels},
+		{"Movements", testMovements},
+		{"Alerts", testAlerts},
+		{"TransferCRUD", testTransferCRUD},
+		{"Lots", testLots},
+		{"Serials", testSerials},
+		{"Counts", testCounts},
+		{"Holds", testHolds},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newStore(t)
+			t.Cleanup(func() {
+				if err := s.Close(); err != nil {
+					t.Errorf("Close: %v", err)
+				}
+			})
+			tt.fn(t, s)
+		})
+	}
+}
+
+func update(t *testing.T, s store.Store, fn func(store.Tx) error) {
+	t.Helper()
+	if err := s.Update(context.Background(), fn); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+}
+
+func view(t *testing.T, s store.Store, fn func(store.Tx) error) {
+	t.Helper()
+	if err := s.View(context.Background(), fn); err != nil {
+		t.Fatalf("View: %v", err)
+	}
+}
+
+func wantErr(t *testing.T, what string, got, want error) {
+	t.Helper()
+	if !errors.Is(got, want) {
+		t.Errorf("%s: got error %v, want %v", what, got, want)
+	}
+}
+
+func wantEqual(t *testing.T, what string, got, want proto.Message) {
+	t.Helper()
+	if !proto.Equal(got, want) {
+		t.Errorf("%s: got %v, want %v", what, got, want)
+	}
+}
+
+func testProductCRUD(t *testing.T, s store.Store) {
+	p := &pb.Product{Id: "p1", Name: "Widget", Price: 2.5, InventoryLevel: 7}
+	update(t, s, func(tx store.Tx) error { return tx.Products().Create(p) })
+
+	update(t, s, func(tx store.Tx) error {
+		wantErr(t, "Create duplicate", tx.Products().Create(p), store.ErrAlreadyExists)
+		wantErr(t, "Update missing", tx.Products().Update(&pb.Product{Id: "nope"}), store.ErrNotFound)
+		wantErr(t, "Delete missing", tx.Products().Delete("nope"), store.ErrNotFound)
+		_, err := tx.Products().Get("nope")
+		wantErr(t, "Get missing", err, store.ErrNotFound)
+		return nil
+	})
+
+	view(t, s, func(tx store.Tx) error {
+		got, err := tx.Products().Get("p1")
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		wantEqual(t, "Get", got, p)
+		return nil
+	})
+
+	changed := &pb.Product{Id: "p1", Name: "Gadget", Price: 3, InventoryLevel: 1}
+	update(t, s, func(tx store.Tx) error { return tx.Products().Update(changed) })
+	view(t, s, func(tx store.Tx) error {
+		got, err := tx.Products().Get("p1")
+		if err != nil {
+			t.Fatalf("Get after Update: %v", err)
+		}
+		wantEqual(t, "Get after Update", got, changed)
+		return nil
+	})
+
+	update(t, s, func(tx store.Tx) error { return tx.Products().Delete("p1") })
+	view(t, s, func(tx store.Tx) error {
+		_, err := tx.Products().Get("p1")
+		wantErr(t, "Get after Delete", err, store.ErrNotFound)
+		return nil
+	})
+}
+
+func testProductList(t *testing.T, s store.Store) {
+	update(t, s, func(tx store.Tx) error {
+		for _, id := range []string{"c", "a", "b"} {
+			if err := tx.Products().Create(&pb.Product{Id: id}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	view(t, s, func(tx store.Tx) error {
+		products, err := tx.Products().List()
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		var ids []string
+		for _, p := range products {
+			ids = append(ids, p.Id)
+		}
+		if fmt.Sprint(ids) != "[a b c]" {
+			t.Errorf("List returned %v, want [a b c]", ids)
+		}
+		return nil
+	})
+}
+
+func testOrderCRUD(t *testing.T, s store.Store) {
+	o := &pb.Order{Id: "o1", ProductId: "p1", Quantity: 3, OrderDate: timestamppb.Now()}
+	update(t, s, func(tx store.Tx) error { return tx.Orders().Create(o) })
+
+	update(t, s, func(tx store.Tx) error {
+		wantErr(t, "Create duplicate", tx.Orders().Create(o), store.ErrAlreadyExists)
+		wantErr(t, "Update missing", tx.Orders().Update(&pb.Order{Id: "nope"}), store.ErrNotFound)
+		wantErr(t, "Delete missing", tx.Orders().Delete("nope"), store.ErrNotFound)
+		_, err := tx.Orders().Get("nope")
+		wantErr(t, "Get missing", err, store.ErrNotFound)
+		return nil
+	})
+
+	changed := proto.Clone(o).(*pb.Order)
+	changed.Quantity = 9
+	update(t, s, func(tx store.Tx) error { return tx.Orders().Update(changed) })
+	view(t, s, func(tx store.Tx) error {
+		got, err := tx.Orders().Get("o1")
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		wantEqual(t, "Get after Update", got, changed)
+		return nil
+	})
+
+	update(t, s, func(tx store.Tx) error { return tx.Orders().Delete("o1") })
+	view(t, s, func(tx store.Tx) error {
+		_, err := tx.Orders().Get("o1")
+		wantErr(t, "Get after Delete", err, store.ErrNotFound)
+		return nil
+	})
+}
+
+func testOrderListByProduct(t *testing.T, s store.Store) {
+	update(t, s, func(tx store.Tx) error {
+		for _, o := range []*pb.Order{
+			{Id: "o2", ProductId: "p1", Quantity: 1},
+			{Id: "o1", ProductId: "p1", Quantity: 1},
+			{Id: "o3", ProductId: "p2", Quantity: 1},
+			{Id: "o4", Lines: []*pb.OrderLine{
+				{ProductId: "p2", Quantity: 1},
+				{ProductId: "p1", Quantity: 2},
+			}},
+			{Id: "o5", Lines: []*pb.OrderLine{{ProductId: "p3", Quantity: 1}}},
+		} {
+			if err := tx.Orders().Create(o); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	view(t, s, func(tx store.Tx) error {
+		orders, err := tx.Orders().ListByProduct("p1")
+		if err != nil {
+			t.Fatalf("ListByProduct: %v", err)
+		}
+		var ids []string
+		for _, o := range orders {
+			ids = append(ids, o.Id)
+		}
+		if fmt.Sprint(ids) != "[o1 o2 o4]" {
+			t.Errorf("ListByProduct returned %v, want [o1 o2 o4]", ids)
+		}
+		return nil
+	})
+}
+
+func testOrderQuery(t *testing.T, s store.Store) {
+	base := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	at := func(hours int) *timestamppb.Timestamp {
+		return timestamppb.New(base.Add(time.Duration(hours) * time.Hour))
+	}
+	pending, shipped := pb.OrderStatus_ORDER_STATUS_PENDING, pb.OrderStatus_ORDER_STATUS_SHIPPED
+	update(t, s, func(tx store.Tx) error {
+		for _, o := range []*pb.Order{
+			{Id: "a", OrderDate: at(3), Status: pending, ProductId: "p1", Quantity: 1},
+			{Id: "b", OrderDate: at(1), Status: shipped, ProductId: "p2", Quantity: 5},
+			{Id: "c", OrderDate: at(2), Status: pending, Lines: []*pb.OrderLine{
+				{ProductId: "p1", Quantity: 2},
+				{ProductId: "p2", Quantity: 2, BackorderedQuantity: 1},
+			}},
+			{Id: "d", OrderDate: at(2), Status: shipped, ProductId: "p1", Quantity: 10},
+			{Id: "e", Status: pending, ProductId: "p1", Quantity: 1}, // no date
+		} {
+			if err := tx.Orders().Create(o); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	query := func(q store.OrderQuery) string {
+		t.Helper()
+		var ids []string
+		view(t, s, func(tx store.Tx) error {
+			orders, err := tx.Orders().Query(q)
+			if err != nil {
+				t.Fatalf("Query(%+v): %v", q, err)
+			}
+			for _, o := range orders {
+				ids = append(ids, o.Id)
+			}
+			return nil
+		})
+		return fmt.Sprint(ids)
+	}
+	for _, tt := range []struct {
+		q    store.OrderQuery
+		want string
+	}{
+		{store.OrderQuery{}, "[b c d a]"},
+		{store.OrderQuery{ProductID: "p1"}, "[c d a]"},
+		{store.OrderQuery{ProductID: "p1", Statuses: []pb.OrderStatus{shipped}}, "[d]"},
+		{store.OrderQuery{Statuses: []pb.OrderStatus{pending, pending}}, "[c a]"},
+		{store.OrderQuery{From: at(2).AsTime(), To: at(3).AsTime()}, "[c d]"},
+		{store.OrderQuery{MinQuantity: 4, MaxQuantity: 5}, "[b c]"},
+		{store.OrderQuery{Limit: 2}, "[b c]"},
+		{store.OrderQuery{AfterDate: at(2).AsTime(), AfterID: "c", Limit: 2}, "[d a]"},
+		{store.OrderQuery{ProductID: "p3"}, "[]"},
+		{store.OrderQuery{Backordered: true}, "[c]"},
+		{store.OrderQuery{ProductID: "p2", Backordered: true}, "[c]"},
+		{store.OrderQuery{ProductID: "p1", Backordered: true}, "[]"},
+	} {
+		if got := query(tt.q); got != tt.want {
+			t.Errorf("Query(%+v) = %s, want %s", tt.q, got, tt.want)
+		}
+	}
+
+	// The indexes follow updates and rollbacks.
+	update(t, s, func(tx store.Tx) error {
+		return tx.Orders().Update(&pb.Order{Id: "a", OrderDate: at(3), Status: shipped, ProductId: "p3", Quantity: 1})
+	})
+	errBoom := errors.New("boom")
+	err := s.Update(context.Background(), func(tx store.Tx) error {
+		if err := tx.Orders().Delete("b"); err != nil {
+			return err
+		}
+		return errBoom
+	})
+	wantErr(t, "Update", err, errBoom)
+	if got := query(store.OrderQuery{ProductID: "p1"}); got != "[c d]" {
+		t.Errorf("Query by product after update = %s, want [c d]", got)
+	}
+	if got := query(store.OrderQuery{Statuses: []pb.OrderStatus{shipped}}); got != "[b d a]" {
+		t.Errorf("Query by status after rollback = %s, want [b d a]", got)
+	}
+	update(t, s, func(tx store.Tx) error {
+		return tx.Orders().Update(&pb.Order{Id: "b", OrderDate: at(4), Status: shipped, ProductId: "p2", Quantity: 5})
+	})
+	if got := query(store.OrderQuery{}); got != "[c d a b]" {
+		t.Errorf("Query by date after moving an order = %s, want [c d a b]", got)
+	}
+	if got := query(store.OrderQuery{From: at(3).AsTime(), AfterDate: at(2).AsTime(), AfterID: "c"}); got != "[a b]" {
+		t.Errorf("Query from a date past the cursor = %s, want [a b]", got)
+	}
+}
+
+func testRollback(t *testing.T, s store.Store) {
+	update(t, s, func(tx store.Tx) error {
+		return tx.Products().Create(&pb.Product{Id: "p1", InventoryLevel: 10})
+	})
+
+	errBoom := errors.New("boom")
+	err := s.Update(context.Background(), func(tx store.Tx) error {
+		if err := tx.Products().Update(&pb.Product{Id: "p1", InventoryLevel: 4}); err != nil {
+			return err
+		}
+		if err := tx.Products().Create(&pb.Product{Id: "p2"}); err != nil {
+			return err
+		}
+		if err := tx.Orders().Create(&pb.Order{Id: "o1", ProductId: "p1", Quantity: 6}); err != nil {
+			return err
+		}
+		return errBoom
+	})
+	if err != errBoom {
+		t.Fatalf("Update returned %v, want the function's error unchanged", err)
+	}
+
+	view(t, s, func(tx store.Tx) error {
+		p, err := tx.Products().Get("p1")
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if p.InventoryLevel != 10 {
+			t.Errorf("inventory_level = %d after rollback, want 10", p.InventoryLevel)
+		}
+		_, err = tx.Products().Get("p2")
+		wantErr(t, "Get rolled-back product", err, store.ErrNotFound)
+		_, err = tx.Orders().Get("o1")
+		wantErr(t, "Get rolled-back order", err, store.ErrNotFound)
+		return nil
+	})
+}
+
+func testReadOnlyView(t *testing.T, s store.Store) {
+	update(t, s, func(tx store.Tx) error { return tx.Products().Create(&pb.Product{Id: "p1"}) })
+	err := s.View(context.Background(), func(tx store.Tx) error {
+		return tx.Products().Update(&pb.Product{Id: "p1", Name: "changed"})
+	})
+	wantErr(t, "Update in View", err, store.ErrReadOnly)
+}
+
+func testCopySemantics(t *testing.T, s store.Store) {
+	p := &pb.Product{Id: "p1", Name: "original"}
+	update(t, s, func(tx store.Tx) error { return tx.Products().Create(p) })
+	p.Name = "mutated after create"
+
+	view(t, s, func(tx store.Tx) error {
+		got, err := tx.Products().Get("p1")
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if got.Name != "original" {
+			t.Errorf("stored product changed through caller's message: %q", got.Name)
+		}
+		got.Name = "mutated after get"
+		return nil
+	})
+	view(t, s, func(tx store.Tx) error {
+		got, err := tx.Products().Get("p1")
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if got.Name != "original" {
+			t.Errorf("stored product changed through returned message: %q", got.Name)
+		}
+		return nil
+	})
+}
+
+// testSerializableUpdates runs concurrent read-modify-write transactions on
+// one product; none of the increments may be lost.
+func testSerializableUpdates(t *testing.T, s store.Store) {
+	update(t, s, func(tx store.Tx) error { return tx.Products().Create(&pb.Product{Id: "p1"}) })
+
+	const workers = 20
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := s.Update(context.Background(), func(tx store.Tx) error {
+				p, err := tx.Products().Get("p1")
+				if err != nil {
+					return err
+				}
+				p.InventoryLevel++
+				return tx.Products().Update(p)
+			})
+			if err != nil {
+				t.Errorf("Update: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	view(t, s, func(tx store.Tx) error {
+		p, err := tx.Products().Get("p1")
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if p.InventoryLevel != workers {
+			t.Errorf("inventory_level = %d, want %d", p.InventoryLevel, workers)
+		}
+		return nil
+	})
+}
+
+// testConcurrentEventReads reads the event log from several views while
+// events are appended, as concurrent WatchInventory streams do. Run it
+// with -race.
+func testConcurrentEventReads(t *testing.T, s store.Store) {
+	const readers, appends = 8, 10
+	var wg sync.WaitGroup
+	for i := 0; i < readers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < appends; j++ {
+				err := s.View(context.Background(), func(tx store.Tx) error {
+					first, last, err := tx.Events().Bounds()
+					if err == nil && first > last {
+						t.Errorf("Bounds = %d, %d; first is past last", first, last)
+					}
+					return err
+				})
+				if err != nil {
+					t.Errorf("Bounds: %v", err)
+				}
+			}
+		}()
+	}
+	for i := 0; i < appends; i++ {
+		update(t, s, func(tx store.Tx) error {
+			return tx.Events().Append(&pb.InventoryEvent{ProductId: "p1", Time: timestamppb.Now()})
+		})
+	}
+	wg.Wait()
+
+	view(t, s, func(tx store.Tx) error {
+		first, last, err := tx.Events().Bounds()
+		if err != nil || first != 1 || last != appends {
+			t.Errorf("Bounds = %d, %d, %v; want 1, %d, nil", first, last, err, appends)
+		}
+		return nil
+	})
+}
+
+func testEventLog(t *testing.T, s store.Store) {
+	view(t, s, func(tx store.Tx) error {
+		first, last, err := tx.Events().Bounds()
+		if err != nil || first != 0 || last != 0 {
+			t.Errorf("Bounds of empty log = %d, %d, %v; want 0, 0, nil", first, last, err)
+		}
+		return nil
+	})
+
+	for i := 1; i <= 5; i++ {
+		update(t, s, func(tx store.Tx) error {
+			e := &pb.InventoryEvent{ProductId: "p1", InventoryLevel: int32(i), Time: timestamppb.Now()}
+			if err := tx.Events().Append(e); err != nil {
+				return err
+			}
+			if e.Sequence != uint64(i) {
+				t.Errorf("Append assigned sequence %d, want %d", e.Sequence, i)
+			}
+			return nil
+		})
+	}
+
+	// A rolled-back append must not consume a sequence number.
+	s.Update(context.Background(), func(tx store.Tx) error {
+		tx.Events().Append(&pb.InventoryEvent{ProductId: "p1", Time: timestamppb.Now()})
+		return errors.New("abort")
+	})
+
+	view(t, s, func(tx store.Tx) error {
+		events, err := tx.Events().ListAfter(2, 2)
+		if err != nil {
+			t.Fatalf("ListAfter: %v", err)
+		}
+		if len(events) != 2 || events[0].Sequence != 3 || events[1].Sequence != 4 {
+			t.Errorf("ListAfter(2, 2) returned %v, want sequences 3 and 4", events)
+		}
+		return nil
+	})
+
+	update(t, s, func(tx store.Tx) error { return tx.Events().DeleteBefore(100) })
+	view(t, s, func(tx store.Tx) error {
+		first, last, err := tx.Events().Bounds()
+		if err != nil || first != 5 || last != 5 {
+			t.Errorf("Bounds after DeleteBefore = %d, %d, %v; want the last event kept", first, last, err)
+		}
+		return nil
+	})
+	update(t, s, func(tx store.Tx) error {
+		e := &pb.InventoryEvent{ProductId: "p1", Time: timestamppb.Now()}
+		if err := tx.Events().Append(e); err != nil {
+			return err
+		}
+		if e.Sequence != 6 {
+			t.Errorf("Append after DeleteBefore assigned sequence %d, want 6", e.Sequence)
+		}
+		return nil
+	})
+}
+
+func testIdempotency(t *testing.T, s store.Store) {
+	now := time.Now()
+	record := func(key string, expire time.Time) *pb.IdempotencyRecord {
+		return &pb.IdempotencyRecord{
+			Key:        key,
+			Method:     "/inventory.InventoryService/CreateOrder",
+			CreateTime: timestamppb.New(now),
+			ExpireTime: timestamppb.New(expire),
+		}
+	}
+	update(t, s, func(tx store.Tx) error {
+		if err := tx.Idempotency().Create(record("old", now.Add(-time.Minute))); err != nil {
+			return err
+		}
+		return tx.Idempotency().Create(record("new", now.Add(time.Hour)))
+	})
+	update(t, s, func(tx store.Tx) error {
+		wantErr(t, "Create duplicate", tx.Idempotency().Create(record("new", now)), store.ErrAlreadyExists)
+		wantErr(t, "Update missing", tx.Idempotency().Update(record("missing", now)), store.ErrNotFound)
+		return nil
+	})
+
+	update(t, s, func(tx store.Tx) error {
+		n, err := tx.Idempotency().DeleteExpired(now)
+		if err != nil {
+			return err
+		}
+		if n != 1 {
+			t.Errorf("DeleteExpired removed %d records, want 1", n)
+		}
+		return nil
+	})
+	view(t, s, func(tx store.Tx) error {
+		_, err := tx.Idempotency().Get("old")
+		wantErr(t, "Get expired", err, store.ErrNotFound)
+		got, err := tx.Idempotency().Get("new")
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if !got.ExpireTime.AsTime().Equal(now.Add(time.Hour)) {
+			t.Errorf("Get returned expire_time %v, want %v", got.ExpireTime.AsTime(), now.Add(time.Hour))
+		}
+		return nil
+	})
+}
+
+func testWarehouses(t *testing.T, s store.Store) {
+	east := &pb.Warehouse{Id: "east", Name: "East", Address: "1 Dock Rd"}
+	update(t, s, func(tx store.Tx) error {
+		if err := tx.Warehouses().Create(&pb.Warehouse{Id: "west", Name: "West"}); err != nil {
+			return err
+		}
+		return tx.Warehouses().Create(east)
+	})
+	update(t, s, func(tx store.Tx) error {
+		wantErr(t, "Create duplicate", tx.Warehouses().Create(east), store.ErrAlreadyExists)
+		wantErr(t, "Delete missing", tx.Warehouses().Delete("north"), store.ErrNotFound)
+		return nil
+	})
+	view(t, s, func(tx store.Tx) error {
+		got, err := tx.Warehouses().Get("east")
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		wantEqual(t, "Get", got, east)
+		all, err := tx.Warehouses().List()
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		if len(all) != 2 || all[0].Id != "east" || all[1].Id != "west" {
+			t.Errorf("List returned %v, want [east west]", all)
+		}
+		return nil
+	})
+	update(t, s, func(tx store.Tx) error { return tx.Warehouses().Delete("east") })
+	view(t, s, func(tx store.Tx) error {
+		_, err := tx.Warehouses().Get("east")
+		wantErr(t, "Get deleted", err, store.ErrNotFound)
+		return nil
+	})
+}
+
+func testStockLevels(t *testing.T, s store.Store) {
+	level := func(product, warehouse string, quantity int32) *pb.StockLevel {
+		return &pb.StockLevel{ProductId: product, WarehouseId: warehouse, Quantity: quantity}
+	}
+	update(t, s, func(tx store.Tx) error {
+		for _, l := range []*pb.StockLevel{
+			level("p2", "east", 4),
+			level("p1", "west", 2),
+			level("p1", "east", 3),
+		} {
+			if err := tx.Stock().Put(l); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	// Put replaces the existing level for the same product and warehouse.
+	update(t, s, func(tx store.Tx) error { return tx.Stock().Put(level("p1", "east", 7)) })
+
+	view(t, s, func(tx store.Tx) error {
+		got, err := tx.Stock().Get("p1", "east")
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		wantEqual(t, "Get", got, level("p1", "east", 7))
+		_, err = tx.Stock().Get("p2", "west")
+		wantErr(t, "Get missing", err, store.ErrNotFound)
+
+		byProduct, err := tx.Stock().ListByProduct("p1")
+		if err != nil {
+			t.Fatalf("ListByProduct: %v", err)
+		}
+		if len(byProduct) != 2 || byProduct[0].WarehouseId != "east" || byProduct[1].WarehouseId != "west" {
+			t.Errorf("ListByProduct returned %v, want east then west", byProduct)
+		}
+		byWarehouse, err := tx.Stock().ListByWarehouse("east")
+		if err != nil {
+			t.Fatalf("ListByWarehouse: %v", err)
+		}
+		if len(byWarehouse) != 2 || byWarehouse[0].ProductId != "p1" || byWarehouse[1].ProductId != "p2" {
+			t.Errorf("ListByWarehouse returned %v, want p1 then p2", byWarehouse)
+		}
+		return nil
+	})
+
+	update(t, s, func(tx store.Tx) error { return tx.Stock().Delete("p1", "east") })
+	update(t, s, func(tx store.Tx) error {
+		wantErr(t, "Delete missing", tx.Stock().Delete("p1", "east"), store.ErrNotFound)
+		return nil
+	})
+	view(t, s, func(tx store.Tx) error {
+		levels, err := tx.Stock().ListByWarehouse("east")
+		if err != nil {
+			t.Fatalf("ListByWarehouse: %v", err)
+		}
+		if len(levels) != 1 || levels[0].ProductId != "p2" {
+			t.Errorf("ListByWarehouse after Delete returned %v, want [p2]", levels)
+		}
+		return nil
+	})
+}
+
+func testMovements(t *testing.T, s store.Store) {
+	movement := func(product, warehouse string, delta int32, reason pb.StockMovementReason) *pb.StockMovement {
+		return &pb.StockMovement{ProductId: product, WarehouseId: warehouse, Delta: delta, Reason: reason}
+	}
+	update(t, s, func(tx store.Tx) error {
+		for _, m := range []*pb.StockMovement{
+			movement("p1", "default", 10, pb.StockMovementReason_RECEIVED),
+			movement("p2", "default", 5, pb.StockMovementReason_RECEIVED),
+			movement("p1", "east", 4, pb.StockMovementReason_RECEIVED),
+			movement("p1", "default", -3, pb.StockMovementReason_ORDER_RESERVED),
+		} {
+			if err := tx.Movements().Append(m); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	// A rolled back append does not use up its sequence number.
+	errRollback := errors.New("rollback")
+	err := s.Update(context.Background(), func(tx store.Tx) error {
+		if err := tx.Movements().Append(movement("p1", "default", 1, pb.StockMovementReason_RECEIVED)); err != nil {
+			return err
+		}
+		return errRollback
+	})
+	wantErr(t, "rolled back Update", err, errRollback)
+	update(t, s, func(tx store.Tx) error {
+		m := movement("p2", "east", 2, pb.StockMovementReason_RECEIVED)
+		if err := tx.Movements().Append(m); err != nil {
+			return err
+		}
+		if m.Sequence != 5 {
+			t.Errorf("Append assigned sequence %d, want 5", m.Sequence)
+		}
+		return nil
+	})
+
+	sequences := func(ms []*pb.StockMovement) []uint64 {
+		var seqs []uint64
+		for _, m := range ms {
+			seqs = append(seqs, m.Sequence)
+		}
+		return seqs
+	}
+	view(t, s, func(tx store.Tx) error {
+		for _, tt := range []struct {
+			name string
+			q    store.MovementQuery
+			want []uint64
+		}{
+			{"all", store.MovementQuery{}, []uint64{1, 2, 3, 4, 5}},
+			{"product", store.MovementQuery{ProductID: "p1"}, []uint64{1, 3, 4}},
+			{"warehouse", store.MovementQuery{WarehouseID: "east"}, []uint64{3, 5}},
+			{"reason", store.MovementQuery{Reason: pb.StockMovementReason_ORDER_RESERVED}, []uint64{4}},
+			{"product and warehouse", store.MovementQuery{ProductID: "p1", WarehouseID: "default"}, []uint64{1, 4}},
+			{"page", store.MovementQuery{AfterSequence: 2, Limit: 2}, []uint64{3, 4}},
+		} {
+			got, err := tx.Movements().Query(tt.q)
+			if err != nil {
+				t.Fatalf("Query %s: %v", tt.name, err)
+			}
+			if !slices.Equal(sequences(got), tt.want) {
+				t.Errorf("Query %s returned %v, want %v", tt.name, sequences(got), tt.want)
+			}
+		}
+
+		totals, err := tx.Movements().Totals("p1")
+		if err != nil {
+			t.Fatalf("Totals: %v", err)
+		}
+		if len(totals) != 2 || totals["default"] != 7 || totals["east"] != 4 {
+			t.Errorf("Totals returned %v, want default:7 east:4", totals)
+		}
+		return nil
+	})
+}
+
+func testAlerts(t *testing.T, s store.Store) {
+	view(t, s, func(tx store.Tx) error {
+		last, err := tx.Alerts().Last()
+		if err != nil {
+			t.Fatalf("Last: %v", err)
+		}
+		if last != 0 {
+			t.Errorf("Last on an empty log returned %d, want 0", last)
+		}
+		return nil
+	})
+	update(t, s, func(tx store.Tx) error {
+		for _, id := range []string{"p1", "p2", "p3"} {
+			if err := tx.Alerts().Append(&pb.Alert{Type: pb.AlertType_LOW_STOCK, ProductId: id}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	view(t, s, func(tx store.Tx) error {
+		last, err := tx.Alerts().Last()
+		if err != nil {
+			t.Fatalf("Last: %v", err)
+		}
+		if last != 3 {
+			t.Errorf("Last returned %d, want 3", last)
+		}
+		alerts, err := tx.Alerts().ListAfter(1, 1)
+		if err != nil {
+			t.Fatalf("ListAfter: %v", err)
+		}
+		if len(alerts) != 1 || alerts[0].Sequence != 2 || alerts[0].ProductId != "p2" {
+			t.Errorf("ListAfter(1, 1) returned %v, want the alert for p2", alerts)
+		}
+		return nil
+	})
+}
+
+func testTransferCRUD(t *testing.T, s store.Store) {
+	transfer := &pb.Transfer{
+		Id:                     "t1",
+		SourceWarehouseId:      "east",
+		DestinationWarehouseId: "west",
+		Lines:                  []*pb.TransferLine{{ProductId: "p1", Quantity: 4}},
+		Status:                 pb.TransferStatus_TRANSFER_STATUS_IN_TRANSIT,
+	}
+	update(t, s, func(tx store.Tx) error { return tx.Transfers().Create(transfer) })
+	update(t, s, func(tx store.Tx) error {
+		wantErr(t, "Create duplicate", tx.Transfers().Create(transfer), store.ErrAlreadyExists)
+		wantErr(t, "Update missing", tx.Transfers().Update(&pb.Transfer{Id: "t2"}), store.ErrNotFound)
+		return nil
+	})
+
+	received := proto.Clone(transfer).(*pb.Transfer)
+	received.Lines[0].ReceivedQuantity = 4
+	received.Status = pb.TransferStatus_TRANSFER_STATUS_RECEIVED
+	update(t, s, func(tx store.Tx) error { return tx.Transfers().Update(received) })
+	view(t, s, func(tx store.Tx) error {
+		got, err := tx.Transfers().Get("t1")
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		wantEqual(t, "Get after Update", got, received)
+		_, err = tx.Transfers().Get("t2")
+		wantErr(t, "Get missing", err, store.ErrNotFound)
+		return nil
+	})
+
+	// Only transfers still in transit are listed.
+	update(t, s, func(tx store.Tx) error {
+		for _, id := range []string{"t3", "t2"} {
+			in := proto.Clone(transfer).(*pb.Transfer)
+			in.Id = id
+			if err := tx.Transfers().Create(in); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	view(t, s, func(tx store.Tx) error {
+		transfers, err := tx.Transfers().ListInTransit()
+		if err != nil {
+			t.Fatalf("ListInTransit: %v", err)
+		}
+		var got []string
+		for _, tr := range transfers {
+			got = append(got, tr.Id)
+		}
+		if fmt.Sprint(got) != "[t2 t3]" {
+			t.Errorf("ListInTransit returned %v, want [t2 t3]", got)
+		}
+		return nil
+	})
+}
+
+func testLots(t *testing.T, s store.Store) {
+	now := time.Now().Truncate(time.Second)
+	lot := func(id, product string, quantity int32, expire time.Duration) *pb.Lot {
+		l := &pb.Lot{Id: id, ProductId: product, WarehouseId: "default", LotNumber: "L-" + id, Quantity: quantity}
+		if expire != 0 {
+			l.ExpireTime = timestamppb.New(now.Add(expire))
+		}
+		return l
+	}
+	update(t, s, func(tx store.Tx) error {
+		for _, l := range []*pb.Lot{
+			lot("a", "p1", 5, 48*time.Hour),
+			lot("b", "p1", 5, time.Hour),
+			lot("c", "p2", 0, -time.Hour),
+			lot("d", "p2", 3, -time.Hour),
+			lot("e", "p1", 2, 0),
+		} {
+			if err := tx.Lots().Create(l); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	update(t, s, func(tx store.Tx) error {
+		wantErr(t, "Create duplicate", tx.Lots().Create(lot("a", "p1", 1, 0)), store.ErrAlreadyExists)
+		wantErr(t, "Update missing", tx.Lots().Update(lot("z", "p1", 1, 0)), store.ErrNotFound)
+		return tx.Lots().Update(lot("a", "p1", 4, 48*time.Hour))
+	})
+
+	ids := func(lots []*pb.Lot) string {
+		var out []string
+		for _, l := range lots {
+			out = append(out, l.Id)
+		}
+		return fmt.Sprint(out)
+	}
+	view(t, s, func(tx store.Tx) error {
+		got, err := tx.Lots().Get("a")
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		wantEqual(t, "Get after Update", got, lot("a", "p1", 4, 48*time.Hour))
+
+		byProduct, err := tx.Lots().ListByProduct("p1")
+		if err != nil {
+			t.Fatalf("ListByProduct: %v", err)
+		}
+		if got := ids(byProduct); got != "[a b e]" {
+			t.Errorf("ListByProduct returned %s, want [a b e]", got)
+		}
+		// Empty lots and lots without an expiry are never expiring.
+		expiring, err := tx.Lots().ListExpiring(now.Add(24 * time.Hour))
+		if err != nil {
+			t.Fatalf("ListExpiring: %v", err)
+		}
+		if got := ids(expiring); got != "[d b]" {
+			t.Errorf("ListExpiring returned %s, want [d b]", got)
+		}
+		return nil
+	})
+
+	update(t, s, func(tx store.Tx) error { return tx.Lots().Delete("a") })
+	view(t, s, func(tx store.Tx) error {
+		_, err := tx.Lots().Get("a")
+		wantErr(t, "Get deleted", err, store.ErrNotFound)
+		return nil
+	})
+}
+
+func testSerials(t *testing.T, s store.Store) {
+	serial := func(product, number string, status pb.SerialStatus) *pb.SerialNumber {
+		return &pb.SerialNumber{ProductId: product, SerialNumber: number, WarehouseId: "default", Status: status}
+	}
+	update(t, s, func(tx store.Tx) error {
+		// The same serial may exist for different products, and a "/" in
+		// either ID must not make two keys collide.
+		for _, sn := range []*pb.SerialNumber{
+			serial("p1", "SN-1", pb.SerialStatus_SERIAL_STATUS_IN_STOCK),
+			serial("p2", "SN-1", pb.SerialStatus_SERIAL_STATUS_IN_STOCK),
+			serial("p/1", "x", pb.SerialStatus_SERIAL_STATUS_IN_STOCK),
+			serial("p", "1/x", pb.SerialStatus_SERIAL_STATUS_IN_STOCK),
+		} {
+			if err := tx.Serials().Create(sn); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	update(t, s, func(tx store.Tx) error {
+		wantErr(t, "Create duplicate",
+			tx.Serials().Create(serial("p1", "SN-1", pb.SerialStatus_SERIAL_STATUS_IN_STOCK)), store.ErrAlreadyExists)
+		wantErr(t, "Update missing",
+			tx.Serials().Update(serial("p1", "SN-2", pb.SerialStatus_SERIAL_STATUS_SOLD)), store.ErrNotFound)
+		sold := serial("p1", "SN-1", pb.SerialStatus_SERIAL_STATUS_SOLD)
+		sold.OrderId = "o1"
+		return tx.Serials().Update(sold)
+	})
+	view(t, s, func(tx store.Tx) error {
+		want := serial("p1", "SN-1", pb.SerialStatus_SERIAL_STATUS_SOLD)
+		want.OrderId = "o1"
+		got, err := tx.Serials().Get("p1", "SN-1")
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		wantEqual(t, "Get after Update", got, want)
+		if got, err = tx.Serials().Get("p2", "SN-1"); err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		wantEqual(t, "Get other product", got, serial("p2", "SN-1", pb.SerialStatus_SERIAL_STATUS_IN_STOCK))
+		if got, err = tx.Serials().Get("p", "1/x"); err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		wantEqual(t, "Get slashed ID", got, serial("p", "1/x", pb.SerialStatus_SERIAL_STATUS_IN_STOCK))
+		_, err = tx.Serials().Get("p3", "SN-1")
+		wantErr(t, "Get missing", err, store.ErrNotFound)
+		return nil
+	})
+}
+
+func testCounts(t *testing.T, s store.Store) {
+	count := func(id, warehouse string, status pb.CountStatus) *pb.CycleCount {
+		return &pb.CycleCount{Id: id, WarehouseId: warehouse, Status: status, Version: 1}
+	}
+	update(t, s, func(tx store.Tx) error {
+		for _, c := range []*pb.CycleCount{
+			count("c", "w1", pb.CountStatus_COUNT_STATUS_OPEN),
+			count("a", "w1", pb.CountStatus_COUNT_STATUS_OPEN),
+			count("b", "w2", pb.CountStatus_COUNT_STATUS_OPEN),
+			count("d", "w1", pb.CountStatus_COUNT_STATUS_FINALIZED),
+		} {
+			if err := tx.Counts().Create(c); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	update(t, s, func(tx store.Tx) error {
+		wantErr(t, "Create duplicate", tx.Counts().Create(count("a", "w1", pb.CountStatus_COUNT_STATUS_OPEN)), store.ErrAlreadyExists)
+		wantErr(t, "Update missing", tx.Counts().Update(count("z", "w1", pb.CountStatus_COUNT_STATUS_OPEN)), store.ErrNotFound)
+		return tx.Counts().Update(count("c", "w1", pb.CountStatus_COUNT_STATUS_CANCELLED))
+	})
+	view(t, s, func(tx store.Tx) error {
+		got, err := tx.Counts().Get("c")
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		wantEqual(t, "Get after Update", got, count("c", "w1", pb.CountStatus_COUNT_STATUS_CANCELLED))
+
+		// Counts leave the open list once they are closed.
+		open, err := tx.Counts().ListOpen("w1")
+		if err != nil {
+			t.Fatalf("ListOpen: %v", err)
+		}
+		if len(open) != 1 || open[0].Id != "a" {
+			t.Errorf("ListOpen returned %v, want [a]", open)
+		}
+		return nil
+	})
+}
+
+func testHolds(t *testing.T, s store.Store) {
+	now := time.Now().Truncate(time.Second)
+	hold := func(id string, status pb.HoldStatus, expire time.Duration) *pb.Hold {
+		return &pb.Hold{Id: id, Status: status, ExpireTime: timestamppb.New(now.Add(expire)), Version: 1}
+	}
+	update(t, s, func(tx store.Tx) error {
+		for _, h := range []*pb.Hold{
+			hold("a", pb.HoldStatus_HOLD_STATUS_ACTIVE, -time.Minute),
+			hold("b", pb.HoldStatus_HOLD_STATUS_ACTIVE, -time.Hour),
+			hold("c", pb.HoldStatus_HOLD_STATUS_ACTIVE, time.Hour),
+			hold("d", pb.HoldStatus_HOLD_STATUS_RELEASED, -time.Hour),
+			hold("e", pb.HoldStatus_HOLD_STATUS_ACTIVE, -time.Minute),
+			{Id: "f", Status: pb.HoldStatus_HOLD_STATUS_ACTIVE, Version: 1}, // never expires
+		} {
+			if err := tx.Holds().Create(h); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	update(t, s, func(tx store.Tx) error {
+		wantErr(t, "Create duplicate", tx.Holds().Create(hold("a", pb.HoldStatus_HOLD_STATUS_ACTIVE, 0)), store.ErrAlreadyExists)
+		wantErr(t, "Update missing", tx.Holds().Update(hold("z", pb.HoldStatus_HOLD_STATUS_ACTIVE, 0)), store.ErrNotFound)
+		return tx.Holds().Update(hold("e", pb.HoldStatus_HOLD_STATUS_EXPIRED, -time.Minute))
+	})
+
+	ids := func(holds []*pb.Hold) string {
+		var out []string
+		for _, h := range holds {
+			out = append(out, h.Id)
+		}
+		return fmt.Sprint(out)
+	}
+	view(t, s, func(tx store.Tx) error {
+		got, err := tx.Holds().Get("e")
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		wantEqual(t, "Get after Update", got, hold("e", pb.HoldStatus_HOLD_STATUS_EXPIRED, -time.Minute))
+
+		// Only active holds with an expiry are listed, soonest expiry first.
+		expired, err := tx.Holds().ListExpired(now, 0)
+		if err != nil {
+			t.Fatalf("ListExpired: %v", err)
+		}
+		if got := ids(expired); got != "[b a]" {
+			t.Errorf("ListExpired returned %s, want [b a]", got)
+		}
+		if expired, err = tx.Holds().ListExpired(now, 1); err != nil {
+			t.Fatalf("ListExpired: %v", err)
+		}
+		if got := ids(expired); got != "[b]" {
+			t.Errorf("ListExpired with limit returned %s, want [b]", got)
+		}
+
+		active, err := tx.Holds().ListActive()
+		if err != nil {
+			t.Fatalf("ListActive: %v", err)
+		}
+		if got := ids(active); got != "[a b c f]" {
+			t.Errorf("ListActive returned %s, want [a b c f]", got)
+		}
+		return nil
+	})
+}